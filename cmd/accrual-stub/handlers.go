@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+)
+
+type handlers struct {
+	store *store
+	// Price of the single good unknown orders are registered with, if positive.
+	autoPrice decimal.Decimal
+}
+
+type registerOrder struct {
+	Order string `json:"order"`
+	Goods []Good `json:"goods"`
+}
+
+// Get order accrual info (GET /api/orders/{number} HTTP/1.1).
+func (h *handlers) getOrder(w http.ResponseWriter, r *http.Request) {
+	number := chi.URLParam(r, "number")
+
+	info, err := h.store.getOrder(number)
+	if errors.Is(err, errNotFound) && h.autoPrice.IsPositive() {
+		goods := []Good{{Description: "Auto", Price: h.autoPrice}}
+		if err = h.store.registerOrder(number, goods); err != nil && !errors.Is(err, errAlreadyExists) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		info, err = h.store.getOrder(number)
+	}
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err = json.NewEncoder(w).Encode(info); err != nil {
+		log.Printf("encode order %s: %v", number, err)
+	}
+}
+
+// Register order (POST /api/orders HTTP/1.1).
+func (h *handlers) registerOrder(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var p registerOrder
+
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if p.Order == "" {
+		http.Error(w, "order required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.registerOrder(p.Order, p.Goods); err != nil {
+		if errors.Is(err, errAlreadyExists) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Register reward rule (POST /api/goods HTTP/1.1).
+func (h *handlers) registerRule(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var rule Rule
+
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if rule.Match == "" || !rule.Reward.IsPositive() ||
+		(rule.RewardType != rewardPercent && rule.RewardType != rewardPoints) {
+		http.Error(w, "invalid rule", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.addRule(rule); err != nil {
+		if errors.Is(err, errAlreadyExists) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
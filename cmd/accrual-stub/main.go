@@ -0,0 +1,109 @@
+// Accrual-stub is a local stand-in for the accrual system described in
+// SPECIFICATION.md. Registered orders are REGISTERED at first, then
+// PROCESSING and finally PROCESSED with the accrual calculated by
+// the reward rules, or INVALID if the order number fails the Luhn check.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/shopspring/decimal"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	address := flag.String("a", "127.0.0.1:8080", "server startup address")
+	rulesPath := flag.String("rules", "", "path to the JSON file with reward rules")
+	limit := flag.Int("rpm", 0, "requests per minute allowed to get orders, 0 is unlimited")
+	processingAfter := flag.Duration("processing-after", time.Second, "time before registered order is processing")
+	processedAfter := flag.Duration("processed-after", 2*time.Second, "time before registered order is processed")
+	autoPrice := flag.String("auto-price", "0", "register unknown orders with a good of this price if positive")
+	flag.Parse()
+
+	if v, ok := os.LookupEnv("RUN_ADDRESS"); ok {
+		*address = v
+	}
+
+	price, err := decimal.NewFromString(*autoPrice)
+	if err != nil {
+		return fmt.Errorf("parse auto price: %w", err)
+	}
+
+	rules, err := loadRules(*rulesPath)
+	if err != nil {
+		return fmt.Errorf("load rules: %w", err)
+	}
+
+	h := &handlers{
+		store:     newStore(rules, *processingAfter, *processedAfter),
+		autoPrice: price,
+	}
+
+	router := chi.NewRouter()
+	router.Use(middleware.Logger)
+	router.Use(middleware.Recoverer)
+	router.With(throttle(*limit)).Get("/api/orders/{number}", h.getOrder)
+	router.Post("/api/orders", h.registerOrder)
+	router.Post("/api/goods", h.registerRule)
+
+	hs := &http.Server{
+		Addr:              *address,
+		ReadHeaderTimeout: 5 * time.Second,
+		Handler:           router,
+	}
+
+	// Graceful shutdown.
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+		<-sig
+
+		if err := hs.Close(); err != nil {
+			log.Printf("close server: %v", err)
+		}
+	}()
+
+	log.Printf("Accrual stub is running at %v", *address)
+	if err = hs.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("run server failed: %w", err)
+	}
+
+	return nil
+}
+
+// loadRules reads reward rules in the format of POST /api/goods requests.
+func loadRules(path string) ([]Rule, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []Rule
+
+	if err = json.NewDecoder(file).Decode(&rules); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/config"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/infrastructure/accrual"
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStub(t *testing.T) {
+	rules := []Rule{
+		{Match: "Bork", Reward: decimal.NewFromInt(10), RewardType: rewardPercent},
+		{Match: "Acer", Reward: decimal.NewFromInt(20), RewardType: rewardPoints},
+	}
+
+	st := newStore(rules, time.Second, 2*time.Second)
+
+	now := time.Now()
+	st.now = func() time.Time { return now }
+
+	h := &handlers{store: st}
+
+	router := chi.NewRouter()
+	router.With(throttle(5)).Get("/api/orders/{number}", h.getOrder)
+	router.Post("/api/orders", h.registerOrder)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	client, err := accrual.NewClient(&config.Config{
+		Accrual: config.Accrual{Address: server.URL, Timeout: time.Second},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	// Register orders.
+	res, err := server.Client().Post(server.URL+"/api/orders", "application/json", strings.NewReader(
+		`{"order": "79927398713", "goods": [{"description": "Чайник Bork", "price": 7000}, {"description": "Acer", "price": 1}]}`,
+	))
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, 202, res.StatusCode)
+
+	require.NoError(t, st.registerOrder("79927398710", nil))
+
+	_, err = client.GetOrderInfo(ctx, "12345678903")
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+
+	info, err := client.GetOrderInfo(ctx, "79927398713")
+	require.NoError(t, err)
+	assert.Equal(t, entities.PROCESSING, info.Status)
+
+	now = now.Add(2 * time.Second)
+
+	info, err = client.GetOrderInfo(ctx, "79927398713")
+	require.NoError(t, err)
+	assert.Equal(t, entities.PROCESSED, info.Status)
+	assert.True(t, decimal.NewFromInt(720).Equal(info.Accrual), "got %s", info.Accrual)
+
+	info, err = client.GetOrderInfo(ctx, "79927398710")
+	require.NoError(t, err)
+	assert.Equal(t, entities.INVALID, info.Status)
+
+	// The limit is 5 requests per minute.
+	_, _ = client.GetOrderInfo(ctx, "79927398713")
+
+	_, err = client.GetOrderInfo(ctx, "79927398713")
+
+	var rateLimitErr *errs.RateLimitError
+	require.ErrorAs(t, err, &rateLimitErr)
+	assert.Equal(t, 5, rateLimitErr.RequestsPerMinute)
+	assert.Greater(t, rateLimitErr.RetryAfter, time.Duration(0))
+}
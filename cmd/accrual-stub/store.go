@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/interface/api/rest/response/accrual"
+	"github.com/KretovDmitry/gophermart/pkg/luhn"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	errNotFound      = errors.New("not found")
+	errAlreadyExists = errors.New("already exists")
+)
+
+// Reward types of the goods rules.
+const (
+	rewardPercent = "%"
+	rewardPoints  = "pt"
+)
+
+// Rule rewards goods with description containing Match.
+type Rule struct {
+	Match      string          `json:"match"`
+	Reward     decimal.Decimal `json:"reward"`
+	RewardType string          `json:"reward_type"`
+}
+
+// Good of the registered order.
+type Good struct {
+	Description string          `json:"description"`
+	Price       decimal.Decimal `json:"price"`
+}
+
+type order struct {
+	number       string
+	goods        []Good
+	registeredAt time.Time
+}
+
+// store keeps registered orders and reward rules in memory.
+// Order status is derived from the time passed since the registration.
+type store struct {
+	mu              sync.Mutex
+	orders          map[string]*order
+	rules           []Rule
+	processingAfter time.Duration
+	processedAfter  time.Duration
+	now             func() time.Time
+}
+
+func newStore(rules []Rule, processingAfter, processedAfter time.Duration) *store {
+	return &store{
+		orders:          make(map[string]*order),
+		rules:           rules,
+		processingAfter: processingAfter,
+		processedAfter:  processedAfter,
+		now:             time.Now,
+	}
+}
+
+func (s *store) addRule(rule Rule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range s.rules {
+		if r.Match == rule.Match {
+			return errAlreadyExists
+		}
+	}
+
+	s.rules = append(s.rules, rule)
+
+	return nil
+}
+
+func (s *store) registerOrder(number string, goods []Good) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.orders[number]; ok {
+		return errAlreadyExists
+	}
+
+	s.orders[number] = &order{number: number, goods: goods, registeredAt: s.now()}
+
+	return nil
+}
+
+func (s *store) getOrder(number string) (*accrual.UpdateOrderInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orders[number]
+	if !ok {
+		return nil, errNotFound
+	}
+
+	info := &accrual.UpdateOrderInfo{Order: o.number}
+
+	switch elapsed := s.now().Sub(o.registeredAt); {
+	case elapsed < s.processingAfter:
+		info.Status = accrual.REGISTERED
+	case elapsed < s.processedAfter:
+		info.Status = accrual.PROCESSING
+	case luhn.Validate(o.number) != nil:
+		info.Status = accrual.INVALID
+	default:
+		info.Status = accrual.PROCESSED
+		info.Accrual = s.calculate(o.goods)
+	}
+
+	return info, nil
+}
+
+// calculate sums rewards for the goods using the first matching rule.
+func (s *store) calculate(goods []Good) decimal.Decimal {
+	sum := decimal.Zero
+
+	for _, good := range goods {
+		for _, rule := range s.rules {
+			if !strings.Contains(good.Description, rule.Match) {
+				continue
+			}
+
+			switch rule.RewardType {
+			case rewardPercent:
+				sum = sum.Add(good.Price.Mul(rule.Reward).Div(decimal.NewFromInt(100)))
+			case rewardPoints:
+				sum = sum.Add(rule.Reward)
+			}
+
+			break
+		}
+	}
+
+	return sum
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// throttle allows no more than limit requests per minute in fixed windows
+// responding like the accrual system when the limit is exceeded.
+func throttle(limit int) func(http.Handler) http.Handler {
+	var (
+		mu          sync.Mutex
+		count       int
+		windowStart = time.Now()
+	)
+
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			now := time.Now()
+			if now.Sub(windowStart) >= time.Minute {
+				windowStart, count = now, 0
+			}
+			count++
+			retryAfter := windowStart.Add(time.Minute).Sub(now)
+			exceeded := count > limit
+			mu.Unlock()
+
+			if exceeded {
+				seconds := int(retryAfter.Round(time.Second) / time.Second)
+				w.Header().Set("Content-Type", "text/plain")
+				w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+				w.WriteHeader(http.StatusTooManyRequests)
+				fmt.Fprintf(w, "No more than %d requests per minute allowed", limit)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/KretovDmitry/gophermart/internal/application/services"
 	"github.com/KretovDmitry/gophermart/internal/config"
+	"github.com/KretovDmitry/gophermart/internal/infrastructure/accrual"
 	"github.com/KretovDmitry/gophermart/internal/infrastructure/db/postgres"
 	rest "github.com/KretovDmitry/gophermart/internal/interface/api/rest/chi"
 	"github.com/KretovDmitry/gophermart/internal/interface/api/rest/middleware"
@@ -83,21 +84,70 @@ func run() error {
 	if err != nil {
 		return fmt.Errorf("failed to init order repository: %w", err)
 	}
+	idempotencyRepo, err := postgres.NewIdempotencyRepository(db, trmsql.DefaultCtxGetter, logger)
+	if err != nil {
+		return fmt.Errorf("failed to init idempotency repository: %w", err)
+	}
+	holdRepo, err := postgres.NewHoldRepository(db, trmsql.DefaultCtxGetter, logger)
+	if err != nil {
+		return fmt.Errorf("failed to init hold repository: %w", err)
+	}
+	deadLetterRepo, err := postgres.NewDeadLetterRepository(db, trmsql.DefaultCtxGetter, logger)
+	if err != nil {
+		return fmt.Errorf("failed to init dead letter repository: %w", err)
+	}
 
 	// Init services.
 	authService, err := services.NewAuthService(userRepo, accountRepo, trManager, logger, cfg)
 	if err != nil {
 		return fmt.Errorf("failed to init auth service: %w", err)
 	}
-	accountService, err := services.NewAccountService(accountRepo, orderRepo, trManager, logger)
+	accountService, err := services.NewAccountService(accountRepo, orderRepo, holdRepo, trManager, cfg, logger)
 	if err != nil {
 		return fmt.Errorf("failed to init account service: %w", err)
 	}
-	orderService, err := services.NewOrderService(orderRepo, logger)
+	idempotencyService, err := services.NewIdempotencyService(idempotencyRepo, cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to init idempotency service: %w", err)
+	}
+	orderService, err := services.NewOrderService(orderRepo, trManager, logger)
 	if err != nil {
 		return fmt.Errorf("failed to init order service: %w", err)
 	}
 
+	// Init accrual system client and service polling it.
+	accrualClient, err := accrual.NewClient(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to init accrual client: %w", err)
+	}
+	orderListener, err := postgres.NewOrderListener(cfg.DSN, logger)
+	if err != nil {
+		return fmt.Errorf("failed to init order listener: %w", err)
+	}
+	accrualService, err := services.NewAccrualService(
+		orderRepo, accountRepo, deadLetterRepo, orderListener, accrualClient, trManager, cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to init accrual service: %w", err)
+	}
+
+	// Init job expiring orders never resolved by the accrual system.
+	expiryService, err := services.NewExpiryService(orderRepo, cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to init expiry service: %w", err)
+	}
+
+	// Init job releasing expired holds.
+	holdExpiryService, err := services.NewHoldExpiryService(accountService, cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to init hold expiry service: %w", err)
+	}
+
+	// Init job deleting expired idempotency keys.
+	keyExpiryService, err := services.NewKeyExpiryService(idempotencyRepo, cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to init idempotency key expiry service: %w", err)
+	}
+
 	// Create root router.
 	router := rest.InitChi(logger)
 
@@ -116,11 +166,30 @@ func run() error {
 	})
 
 	// Init and group handlers for account routes.
+	// Withdrawals retried with the same Idempotency-Key are replayed.
 	rest.NewAccountController(accountService, logger, rest.ChiServerOptions{
 		BaseURL:     "/api/user",
 		BaseRouter:  router,
 		Middlewares: []rest.MiddlewareFunc{middleware.Middleware(authService)},
-	})
+	}, middleware.Idempotency(idempotencyService, logger))
+
+	// Init handlers for updates pushed by the accrual system if enabled.
+	if cfg.Accrual.WebhookSecret != "" {
+		rest.NewAccrualController(accrualService, logger, rest.ChiServerOptions{
+			BaseURL:     "/api/internal/accrual",
+			BaseRouter:  router,
+			Middlewares: []rest.MiddlewareFunc{middleware.Signature(cfg.Accrual.WebhookSecret)},
+		})
+	}
+
+	// Init handlers for admin routes if enabled.
+	if cfg.Admin.Token != "" {
+		rest.NewAdminController(accrualService, logger, rest.ChiServerOptions{
+			BaseURL:     "/api/admin",
+			BaseRouter:  router,
+			Middlewares: []rest.MiddlewareFunc{middleware.AdminToken(cfg.Admin.Token)},
+		})
+	}
 
 	// Build HTTP server.
 	hs := &http.Server{
@@ -148,14 +217,18 @@ func run() error {
 		serverStopCtx()
 	}()
 
-	accrualService, err := services.NewAccrualService(orderRepo, accountRepo, trManager, cfg, logger)
-	if err != nil {
-		return fmt.Errorf("failed to init accrual service: %w", err)
-	}
-
-	go accrualService.Run(serverCtx)
+	accrualService.Run(serverCtx)
 	defer accrualService.Stop()
 
+	expiryService.Run(serverCtx)
+	defer expiryService.Stop()
+
+	keyExpiryService.Run(serverCtx)
+	defer keyExpiryService.Stop()
+
+	holdExpiryService.Run(serverCtx)
+	defer holdExpiryService.Stop()
+
 	// Start the HTTP server with graceful shutdown.
 	logger.Infof("Server %v is running at %v", Version, cfg.HTTPServer.Address)
 	if err = hs.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
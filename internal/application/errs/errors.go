@@ -14,6 +14,12 @@ var (
 	ErrNotEnoughFunds     = errors.New("not enough funds")
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrInvalidOrderNumber = errors.New("invalid order number")
+	ErrInvalidTransition  = errors.New("invalid order status transition")
+	ErrOrderNotFound      = errors.New("order not found")
+	ErrHoldNotFound       = errors.New("hold not found")
+	ErrMalformedResponse  = errors.New("malformed response")
+	ErrIdempotencyKeyUsed = errors.New("idempotency key used with another request")
+	ErrRequestTooLarge    = errors.New("request body too large")
 )
 
 // Type just for murshallig purpose.
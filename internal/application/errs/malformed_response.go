@@ -0,0 +1,26 @@
+package errs
+
+import "fmt"
+
+// MalformedResponseError describes the response of the external API
+// that can't be understood. It matches ErrMalformedResponse with errors.Is.
+type MalformedResponseError struct {
+	// Status code of the response.
+	StatusCode int
+	// Raw response body.
+	Body string
+	// Why the response can't be understood.
+	Err error
+}
+
+func (e *MalformedResponseError) Error() string {
+	return fmt.Sprintf("%s [%d]: %v", ErrMalformedResponse, e.StatusCode, e.Err)
+}
+
+func (e *MalformedResponseError) Is(target error) bool {
+	return target == ErrMalformedResponse
+}
+
+func (e *MalformedResponseError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,36 @@
+package errs
+
+import (
+	"fmt"
+	"time"
+)
+
+// RateLimitError describes the limit advertised by the rate limited API.
+// It matches ErrRateLimit with errors.Is.
+type RateLimitError struct {
+	// How long to wait before the next call.
+	RetryAfter time.Duration
+	// Number of allowed requests per minute, zero if unknown.
+	RequestsPerMinute int
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RequestsPerMinute > 0 {
+		return fmt.Sprintf("%s: no more than %d requests per minute, retry after %s",
+			ErrRateLimit, e.RequestsPerMinute, e.RetryAfter)
+	}
+	return fmt.Sprintf("%s: retry after %s", ErrRateLimit, e.RetryAfter)
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimit
+}
+
+// Interval returns the interval between calls satisfying the limit,
+// zero if the limit is unknown.
+func (e *RateLimitError) Interval() time.Duration {
+	if e.RequestsPerMinute <= 0 {
+		return 0
+	}
+	return time.Minute / time.Duration(e.RequestsPerMinute)
+}
@@ -13,4 +13,9 @@ type AccountService interface {
 	GetAccount(context.Context, user.ID) (*entities.Account, error)
 	Withdraw(context.Context, *params.Withdraw) error
 	GetWithdrawals(context.Context, user.ID) ([]*entities.Withdrawal, error)
+	GetStatement(context.Context, *params.Statement) ([]*entities.StatementEntry, *params.Cursor, error)
+	CreateHold(context.Context, *params.Hold) (*entities.Hold, error)
+	CaptureHold(ctx context.Context, id user.ID, holdID int) (*entities.Hold, error)
+	ReleaseHold(ctx context.Context, id user.ID, holdID int) (*entities.Hold, error)
+	ReleaseExpiredHolds(context.Context) (int, error)
 }
@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+)
+
+// AccrualClient represents the accrual system API.
+type AccrualClient interface {
+	// GetOrderInfo returns errs.ErrNotFound if the order is not registered
+	// in the accrual system and *errs.RateLimitError if the rate limit is exceeded.
+	GetOrderInfo(context.Context, entities.OrderNumber) (*entities.UpdateOrderInfo, error)
+}
@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/pkg/breaker"
+)
+
+// AccrualService represents all service actions.
+type AccrualService interface {
+	ApplyUpdate(context.Context, *entities.UpdateOrderInfo) error
+	CircuitState() breaker.State
+	GetDeadLetters(context.Context) ([]*entities.DeadLetter, error)
+	ReplayDeadLetter(ctx context.Context, id int) error
+	DiscardDeadLetter(ctx context.Context, id int) error
+}
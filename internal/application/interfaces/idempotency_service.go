@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+)
+
+// IdempotencyService represents all service actions.
+type IdempotencyService interface {
+	Begin(context.Context, *entities.IdempotencyKey) (*entities.IdempotentResponse, error)
+	Finish(context.Context, *entities.IdempotencyKey, *entities.IdempotentResponse) error
+}
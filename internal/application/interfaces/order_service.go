@@ -3,6 +3,7 @@ package interfaces
 import (
 	"context"
 
+	"github.com/KretovDmitry/gophermart/internal/application/params"
 	"github.com/KretovDmitry/gophermart/internal/domain/entities"
 	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
 )
@@ -10,5 +11,9 @@ import (
 // OrderService represents all service actions.
 type OrderService interface {
 	CreateOrder(context.Context, user.ID, entities.OrderNumber) error
-	GetOrders(context.Context, user.ID) ([]*entities.Order, error)
+	CreateOrders(context.Context, user.ID, []string) ([]*entities.OrderUpload, error)
+	GetOrders(context.Context, *params.ListOrders) ([]*entities.Order, *params.Cursor, error)
+	GetOrder(context.Context, user.ID, entities.OrderNumber) (*entities.Order, error)
+	GetOrderHistory(context.Context, user.ID, entities.OrderNumber) ([]*entities.OrderStatusChange, error)
+	CancelOrder(context.Context, user.ID, entities.OrderNumber) error
 }
@@ -0,0 +1,49 @@
+package params
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+)
+
+// Cursor is the position in the list sorted by time,
+// the ID breaks ties. It is opaque for clients.
+type Cursor struct {
+	At time.Time
+	ID int
+}
+
+func (c *Cursor) String() string {
+	raw := fmt.Sprintf("%d:%d", c.At.UnixNano(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor parses the cursor given to the client before.
+func ParseCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid cursor", errs.ErrInvalidRequest)
+	}
+
+	at, id, found := strings.Cut(string(raw), ":")
+	if !found {
+		return nil, fmt.Errorf("%w: invalid cursor", errs.ErrInvalidRequest)
+	}
+
+	nanos, err := strconv.ParseInt(at, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid cursor", errs.ErrInvalidRequest)
+	}
+
+	cursor := &Cursor{At: time.Unix(0, nanos).UTC()}
+
+	if cursor.ID, err = strconv.Atoi(id); err != nil {
+		return nil, fmt.Errorf("%w: invalid cursor", errs.ErrInvalidRequest)
+	}
+
+	return cursor, nil
+}
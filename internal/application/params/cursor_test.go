@@ -0,0 +1,23 @@
+package params
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	cursor := &Cursor{At: time.Date(2020, 12, 10, 15, 15, 45, 123456000, time.UTC), ID: 42}
+
+	got, err := ParseCursor(cursor.String())
+	require.NoError(t, err)
+	assert.Equal(t, cursor, got)
+
+	for _, s := range []string{"", "!", "MTIz", "YTox"} {
+		_, err = ParseCursor(s)
+		assert.ErrorIs(t, err, errs.ErrInvalidRequest, s)
+	}
+}
@@ -0,0 +1,17 @@
+package params
+
+import (
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
+	"github.com/shopspring/decimal"
+)
+
+type Hold struct {
+	UserID user.ID
+	Order  entities.OrderNumber
+	Sum    decimal.Decimal
+	// How long the funds are reserved.
+	TTL time.Duration
+}
@@ -0,0 +1,31 @@
+package params
+
+import (
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
+)
+
+type Sort string
+
+const (
+	// Newest first.
+	SortDesc Sort = "desc"
+	// Oldest first.
+	SortAsc Sort = "asc"
+)
+
+// ListOrders filters user's orders and pages through them by upload time.
+type ListOrders struct {
+	UserID user.ID
+	// Page size. Zero returns all the orders.
+	Limit int
+	// Position of the last order of the previous page, nil for the first page.
+	After *Cursor
+	// Statuses to return. Empty returns any.
+	Statuses []entities.OrderStatus
+	// Orders uploaded at or after From and before To. Zero time is not bound.
+	From, To time.Time
+	Sort     Sort
+}
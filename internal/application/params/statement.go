@@ -0,0 +1,19 @@
+package params
+
+import (
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
+)
+
+// Statement filters user's account operations and pages through them by processing time.
+type Statement struct {
+	UserID user.ID
+	// Page size. Zero returns all the operations.
+	Limit int
+	// Position of the last operation of the previous page, nil for the first page.
+	After *Cursor
+	// Operations processed at or after From and before To. Zero time is not bound.
+	From, To time.Time
+	Sort     Sort
+}
@@ -3,9 +3,12 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
 	"github.com/KretovDmitry/gophermart/internal/application/interfaces"
 	"github.com/KretovDmitry/gophermart/internal/application/params"
+	"github.com/KretovDmitry/gophermart/internal/config"
 	"github.com/KretovDmitry/gophermart/internal/domain/entities"
 	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
 	"github.com/KretovDmitry/gophermart/internal/domain/repositories"
@@ -16,23 +19,35 @@ import (
 type AccountService struct {
 	accountRepo repositories.AccountRepository
 	orderRepo   repositories.OrderRepository
+	holdRepo    repositories.HoldRepository
 	trm         *manager.Manager
+	config      *config.Config
 	logger      logger.Logger
 }
 
 func NewAccountService(
 	accountRepository repositories.AccountRepository,
 	orderRepository repositories.OrderRepository,
+	holdRepository repositories.HoldRepository,
 	trm *manager.Manager,
+	config *config.Config,
 	logger logger.Logger,
 ) (*AccountService, error) {
+	if holdRepository == nil {
+		return nil, errors.New("nil dependency: hold repository")
+	}
 	if trm == nil {
 		return nil, errors.New("nil dependency: transaction manager")
 	}
+	if config == nil {
+		return nil, errors.New("nil dependency: config")
+	}
 	return &AccountService{
 		accountRepo: accountRepository,
 		orderRepo:   orderRepository,
+		holdRepo:    holdRepository,
 		trm:         trm,
+		config:      config,
 		logger:      logger,
 	}, nil
 }
@@ -47,8 +62,8 @@ func (s *AccountService) Withdraw(ctx context.Context, params *params.Withdraw)
 	return s.trm.Do(ctx, func(ctx context.Context) error {
 		var err error
 
-		// Createe new order.
-		if err = s.orderRepo.CreateOrder(ctx, params.UserID, params.Order); err != nil {
+		// Create new order never checked in the accrual system.
+		if err = s.orderRepo.CreateWithdrawalOrder(ctx, params.UserID, params.Order); err != nil {
 			return err
 		}
 
@@ -71,3 +86,216 @@ func (s *AccountService) Withdraw(ctx context.Context, params *params.Withdraw)
 func (s *AccountService) GetWithdrawals(ctx context.Context, id user.ID) ([]*entities.Withdrawal, error) {
 	return s.accountRepo.GetWithdrawalsByUserID(ctx, id)
 }
+
+// Get page of user's account statement and the cursor of the next one, nil if it is the last page.
+func (s *AccountService) GetStatement(
+	ctx context.Context, p *params.Statement,
+) ([]*entities.StatementEntry, *params.Cursor, error) {
+	if p.Limit == 0 {
+		entries, err := s.accountRepo.GetStatement(ctx, p)
+		return entries, nil, err
+	}
+
+	// One more operation tells if there is the next page.
+	page := *p
+	page.Limit++
+
+	entries, err := s.accountRepo.GetStatement(ctx, &page)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(entries) <= p.Limit {
+		return entries, nil, nil
+	}
+
+	entries = entries[:p.Limit]
+	last := entries[len(entries)-1]
+
+	return entries, &params.Cursor{At: last.ProcessedAt, ID: last.ID}, nil
+}
+
+// CreateHold reserves funds for the new order until the hold
+// is captured or released. Expired holds are released by the janitor.
+// The number of the user's order whose holds were all released
+// can be held again.
+func (s *AccountService) CreateHold(ctx context.Context, p *params.Hold) (*entities.Hold, error) {
+	ttl := p.TTL
+	if ttl == 0 {
+		ttl = s.config.Holds.DefaultTTL
+	}
+	if ttl < 0 || ttl > s.config.Holds.MaxTTL {
+		return nil, fmt.Errorf("%w: hold ttl must be positive and at most %s",
+			errs.ErrInvalidRequest, s.config.Holds.MaxTTL)
+	}
+
+	hold := &entities.Hold{UserID: p.UserID, Order: p.Order, Sum: p.Sum}
+
+	err := s.trm.Do(ctx, func(ctx context.Context) error {
+		var err error
+
+		// Create new order never checked in the accrual system.
+		err = s.orderRepo.CreateWithdrawalOrder(ctx, p.UserID, p.Order)
+		if errors.Is(err, errs.ErrDataConflict) {
+			// Reuse the order of the released holds.
+			lockErr := s.holdRepo.LockReleasedOrder(ctx, p.UserID, p.Order)
+			if lockErr == nil {
+				err = nil
+			} else if !errors.Is(lockErr, errs.ErrNotFound) {
+				return fmt.Errorf("lock released order: %w", lockErr)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		// Move funds from the balance to the held ones.
+		if err = s.accountRepo.HoldFunds(ctx, p.UserID, p.Sum); err != nil {
+			return err
+		}
+
+		if err = s.holdRepo.CreateHold(ctx, hold, ttl); err != nil {
+			return fmt.Errorf("create hold: %w", err)
+		}
+
+		// Write hold to the operations history table.
+		holdOperation := entities.NewHoldOperation(p.UserID, p.Order, p.Sum)
+
+		if err = s.accountRepo.SaveAccountOperation(ctx, holdOperation); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// CaptureHold withdraws the funds of the user's active hold.
+// Expired holds can't be captured.
+func (s *AccountService) CaptureHold(ctx context.Context, id user.ID, holdID int) (*entities.Hold, error) {
+	return s.finishHold(ctx, id, holdID, entities.HoldCaptured)
+}
+
+// ReleaseHold returns the funds of the user's active hold to the balance.
+func (s *AccountService) ReleaseHold(ctx context.Context, id user.ID, holdID int) (*entities.Hold, error) {
+	return s.finishHold(ctx, id, holdID, entities.HoldReleased)
+}
+
+// ReleaseExpiredHolds releases active holds past their expiry time
+// and returns the number of released holds.
+func (s *AccountService) ReleaseExpiredHolds(ctx context.Context) (int, error) {
+	// Holds expired more than this are released on the next run.
+	const limit = 1000
+
+	ids, err := s.holdRepo.GetExpiredHolds(ctx, limit)
+	if err != nil {
+		return 0, fmt.Errorf("get expired holds: %w", err)
+	}
+
+	released := 0
+	for _, holdID := range ids {
+		var resolved bool
+
+		err = s.trm.Do(ctx, func(ctx context.Context) error {
+			hold, err := s.holdRepo.LockHold(ctx, holdID)
+			if err != nil {
+				return fmt.Errorf("lock hold: %w", err)
+			}
+
+			// Resolved by the user meanwhile.
+			if hold.Status != entities.HoldActive {
+				return nil
+			}
+
+			resolved = true
+
+			return s.resolveHold(ctx, hold, entities.HoldReleased)
+		})
+		if err != nil {
+			return released, fmt.Errorf("release hold %d: %w", holdID, err)
+		}
+
+		if resolved {
+			released++
+		}
+	}
+
+	return released, nil
+}
+
+func (s *AccountService) finishHold(
+	ctx context.Context, id user.ID, holdID int, to entities.HoldStatus,
+) (*entities.Hold, error) {
+	var hold *entities.Hold
+
+	err := s.trm.Do(ctx, func(ctx context.Context) error {
+		var err error
+
+		hold, err = s.holdRepo.LockHold(ctx, holdID)
+		if err != nil {
+			if errors.Is(err, errs.ErrNotFound) {
+				return fmt.Errorf("%w: %d", errs.ErrHoldNotFound, holdID)
+			}
+			return fmt.Errorf("lock hold: %w", err)
+		}
+
+		// Holds of other users are not found either.
+		if hold.UserID != id {
+			return fmt.Errorf("%w: %d", errs.ErrHoldNotFound, holdID)
+		}
+
+		if hold.Status != entities.HoldActive {
+			return fmt.Errorf("%w: hold %d is %s", errs.ErrDataConflict, holdID, hold.Status)
+		}
+
+		if to == entities.HoldCaptured && hold.Expired {
+			return fmt.Errorf("%w: hold %d is expired", errs.ErrDataConflict, holdID)
+		}
+
+		return s.resolveHold(ctx, hold, to)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// resolveHold moves the held funds of the locked active hold
+// according to its final status.
+func (s *AccountService) resolveHold(
+	ctx context.Context, hold *entities.Hold, to entities.HoldStatus,
+) error {
+	var err error
+	var operation *entities.Operation
+
+	switch to {
+	case entities.HoldCaptured:
+		err = s.accountRepo.CaptureHeldFunds(ctx, hold.UserID, hold.Sum)
+		operation = entities.NewCaptureOperation(hold.UserID, hold.Order, hold.Sum)
+	case entities.HoldReleased:
+		err = s.accountRepo.ReleaseHeldFunds(ctx, hold.UserID, hold.Sum)
+		operation = entities.NewReleaseOperation(hold.UserID, hold.Order, hold.Sum)
+	default:
+		return fmt.Errorf("resolve hold to %s", to)
+	}
+	if err != nil {
+		return fmt.Errorf("move held funds: %w", err)
+	}
+
+	if err = s.holdRepo.ResolveHold(ctx, hold.ID, to); err != nil {
+		return fmt.Errorf("resolve hold: %w", err)
+	}
+
+	if err = s.accountRepo.SaveAccountOperation(ctx, operation); err != nil {
+		return fmt.Errorf("save account operation: %w", err)
+	}
+
+	hold.Status = to
+
+	return nil
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/application/params"
+	"github.com/KretovDmitry/gophermart/internal/config"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
+	"github.com/KretovDmitry/gophermart/internal/domain/repositories"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountServiceGetStatementPages(t *testing.T) {
+	processedAt := time.Now()
+
+	entries := []*entities.StatementEntry{
+		{ID: 1, Type: entities.ACCRUAL, Order: "79927398713",
+			Sum: decimal.NewFromInt(500), Balance: decimal.NewFromInt(500), ProcessedAt: processedAt},
+		{ID: 2, Type: entities.WITHDRAWAL, Order: "12345678903",
+			Sum: decimal.NewFromInt(200), Balance: decimal.NewFromInt(300), ProcessedAt: processedAt},
+		{ID: 3, Type: entities.ACCRUAL, Order: "4561261212345467",
+			Sum: decimal.NewFromInt(50), Balance: decimal.NewFromInt(350), ProcessedAt: processedAt},
+	}
+
+	accounts := &stubAccountServiceRepository{statement: entries}
+
+	logger, _ := logger.NewForTest()
+
+	service, err := NewAccountService(accounts, &stubAccountServiceOrderRepository{}, newStubHoldRepository(),
+		manager.Must(stubTrFactory), &config.Config{}, logger)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	p := &params.Statement{UserID: testUserID, Limit: 2, Sort: params.SortAsc}
+
+	// The repository returns one more operation than the page holds.
+	page, next, err := service.GetStatement(ctx, p)
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	assert.Equal(t, []int{1, 2}, statementIDs(page))
+	assert.Equal(t, params.Cursor{At: processedAt, ID: 2}, *next)
+	assert.Equal(t, 3, accounts.requested[0].Limit, "one more operation is requested")
+
+	p.After = next
+	accounts.statement = entries[2:]
+
+	page, next, err = service.GetStatement(ctx, p)
+	require.NoError(t, err)
+	assert.Nil(t, next)
+	assert.Equal(t, []int{3}, statementIDs(page))
+	assert.Equal(t, p.After, accounts.requested[1].After, "the cursor is passed to the repository")
+
+	// All the operations are returned without limit.
+	accounts.statement = entries
+
+	page, next, err = service.GetStatement(ctx, &params.Statement{UserID: testUserID})
+	require.NoError(t, err)
+	assert.Nil(t, next)
+	assert.Equal(t, []int{1, 2, 3}, statementIDs(page))
+	assert.Zero(t, accounts.requested[2].Limit)
+}
+
+func TestAccountServiceHolds(t *testing.T) {
+	accounts := &stubAccountServiceRepository{sum: decimal.NewFromInt(100)}
+	holds := newStubHoldRepository()
+
+	logger, _ := logger.NewForTest()
+
+	cfg := &config.Config{Holds: config.Holds{DefaultTTL: time.Hour, MaxTTL: 24 * time.Hour}}
+
+	service, err := NewAccountService(accounts, &stubAccountServiceOrderRepository{}, holds,
+		manager.Must(stubTrFactory), cfg, logger)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	hold := func(order entities.OrderNumber, sum int64, ttl time.Duration) (*entities.Hold, error) {
+		return service.CreateHold(ctx, &params.Hold{
+			UserID: testUserID, Order: order, Sum: decimal.NewFromInt(sum), TTL: ttl,
+		})
+	}
+
+	captured, err := hold("79927398713", 60, 0)
+	require.NoError(t, err)
+	assert.Equal(t, entities.HoldActive, captured.Status)
+	assert.True(t, accounts.balance().Equal(decimal.NewFromInt(40)), "held funds leave the balance")
+
+	_, err = hold("12345678903", 50, 0)
+	assert.ErrorIs(t, err, errs.ErrNotEnoughFunds)
+
+	_, err = hold("12345678903", 10, 48*time.Hour)
+	assert.ErrorIs(t, err, errs.ErrInvalidRequest)
+
+	_, err = service.CaptureHold(ctx, testUserID+1, captured.ID)
+	assert.ErrorIs(t, err, errs.ErrHoldNotFound, "holds of other users are not found")
+
+	captured, err = service.CaptureHold(ctx, testUserID, captured.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entities.HoldCaptured, captured.Status)
+
+	_, err = service.ReleaseHold(ctx, testUserID, captured.ID)
+	assert.ErrorIs(t, err, errs.ErrDataConflict, "captured hold is final")
+
+	expired, err := hold("4561261212345467", 30, time.Nanosecond)
+	require.NoError(t, err)
+	assert.True(t, accounts.balance().Equal(decimal.NewFromInt(10)))
+
+	_, err = service.CaptureHold(ctx, testUserID, expired.ID)
+	assert.ErrorIs(t, err, errs.ErrDataConflict, "expired hold can't be captured")
+
+	released, err := service.ReleaseExpiredHolds(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, released)
+	assert.Equal(t, entities.HoldReleased, holds.holds[expired.ID].Status)
+	assert.True(t, accounts.balance().Equal(decimal.NewFromInt(40)), "released funds are back")
+	assert.True(t, accounts.held.IsZero())
+
+	_, err = hold("79927398713", 10, 0)
+	assert.ErrorIs(t, err, errs.ErrDataConflict, "number of the captured hold is not reused")
+
+	rehold, err := hold("4561261212345467", 10, 0)
+	require.NoError(t, err, "number of the released hold is reused")
+	assert.Equal(t, entities.HoldActive, rehold.Status)
+	assert.True(t, accounts.balance().Equal(decimal.NewFromInt(30)))
+
+	_, err = hold("4561261212345467", 10, 0)
+	assert.ErrorIs(t, err, errs.ErrDataConflict, "number of the active hold is not reused")
+
+	types := make([]entities.OperationType, len(accounts.operations))
+	for i, op := range accounts.operations {
+		types[i] = op.Type
+	}
+	assert.Equal(t, []entities.OperationType{
+		entities.HOLD, entities.CAPTURE, entities.HOLD, entities.RELEASE, entities.HOLD,
+	}, types)
+}
+
+func statementIDs(entries []*entities.StatementEntry) []int {
+	ids := make([]int, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+// stubAccountServiceRepository returns the given statement recording
+// the requested pages, and moves funds between the balance and held ones.
+type stubAccountServiceRepository struct {
+	repositories.AccountRepository
+
+	mu         sync.Mutex
+	sum        decimal.Decimal
+	held       decimal.Decimal
+	operations []*entities.Operation
+	statement  []*entities.StatementEntry
+	requested  []params.Statement
+}
+
+func (r *stubAccountServiceRepository) balance() decimal.Decimal {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.sum
+}
+
+func (r *stubAccountServiceRepository) GetStatement(
+	_ context.Context, p *params.Statement,
+) ([]*entities.StatementEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requested = append(r.requested, *p)
+
+	return slices.Clone(r.statement), nil
+}
+
+func (r *stubAccountServiceRepository) SaveAccountOperation(_ context.Context, op *entities.Operation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.operations = append(r.operations, op)
+
+	return nil
+}
+
+func (r *stubAccountServiceRepository) HoldFunds(_ context.Context, _ user.ID, sum decimal.Decimal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.sum.LessThan(sum) {
+		return errs.ErrNotEnoughFunds
+	}
+	r.sum = r.sum.Sub(sum)
+	r.held = r.held.Add(sum)
+
+	return nil
+}
+
+func (r *stubAccountServiceRepository) CaptureHeldFunds(_ context.Context, _ user.ID, sum decimal.Decimal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.held = r.held.Sub(sum)
+
+	return nil
+}
+
+func (r *stubAccountServiceRepository) ReleaseHeldFunds(_ context.Context, _ user.ID, sum decimal.Decimal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.held = r.held.Sub(sum)
+	r.sum = r.sum.Add(sum)
+
+	return nil
+}
+
+// stubAccountServiceOrderRepository registers withdrawal orders,
+// each number once.
+type stubAccountServiceOrderRepository struct {
+	repositories.OrderRepository
+
+	mu     sync.Mutex
+	orders []entities.OrderNumber
+}
+
+func (r *stubAccountServiceOrderRepository) CreateWithdrawalOrder(
+	_ context.Context, _ user.ID, num entities.OrderNumber,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if slices.Contains(r.orders, num) {
+		return errs.ErrDataConflict
+	}
+	r.orders = append(r.orders, num)
+
+	return nil
+}
+
+type stubHoldRepository struct {
+	mu    sync.Mutex
+	holds map[int]*entities.Hold
+}
+
+var _ repositories.HoldRepository = (*stubHoldRepository)(nil)
+
+func newStubHoldRepository() *stubHoldRepository {
+	return &stubHoldRepository{holds: make(map[int]*entities.Hold)}
+}
+
+func (r *stubHoldRepository) CreateHold(_ context.Context, hold *entities.Hold, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hold.ID = len(r.holds) + 1
+	hold.Status = entities.HoldActive
+	hold.CreatedAt = time.Now()
+	hold.ExpiresAt = hold.CreatedAt.Add(ttl)
+
+	saved := *hold
+	r.holds[hold.ID] = &saved
+
+	return nil
+}
+
+func (r *stubHoldRepository) LockReleasedOrder(_ context.Context, id user.ID, num entities.OrderNumber) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	released := false
+	for _, hold := range r.holds {
+		if hold.Order != num {
+			continue
+		}
+		if hold.UserID != id || hold.Status != entities.HoldReleased {
+			return errs.ErrNotFound
+		}
+		released = true
+	}
+	if !released {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *stubHoldRepository) LockHold(_ context.Context, id int) (*entities.Hold, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	saved, ok := r.holds[id]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+	hold := *saved
+	hold.Expired = !hold.ExpiresAt.After(time.Now())
+
+	return &hold, nil
+}
+
+func (r *stubHoldRepository) ResolveHold(_ context.Context, id int, status entities.HoldStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.holds[id].Status = status
+
+	return nil
+}
+
+func (r *stubHoldRepository) GetExpiredHolds(_ context.Context, limit int) ([]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]int, 0)
+	for id, hold := range r.holds {
+		if hold.Status == entities.HoldActive && !hold.ExpiresAt.After(time.Now()) && len(ids) < limit {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
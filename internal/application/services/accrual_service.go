@@ -2,20 +2,19 @@ package services
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
-	"net/http/cookiejar"
+	"os"
 	"sync"
 	"time"
 
 	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/application/interfaces"
 	"github.com/KretovDmitry/gophermart/internal/config"
 	"github.com/KretovDmitry/gophermart/internal/domain/entities"
-	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
 	"github.com/KretovDmitry/gophermart/internal/domain/repositories"
-	"github.com/KretovDmitry/gophermart/internal/interface/api/rest/response/accrual"
+	"github.com/KretovDmitry/gophermart/pkg/backoff"
+	"github.com/KretovDmitry/gophermart/pkg/breaker"
 	"github.com/KretovDmitry/gophermart/pkg/limiter"
 	"github.com/KretovDmitry/gophermart/pkg/logger"
 	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
@@ -23,20 +22,31 @@ import (
 )
 
 type AccrualService struct {
-	orderRepo   repositories.OrderRepository
-	accountRepo repositories.AccountRepository
-	trm         *manager.Manager
-	logger      logger.Logger
-	config      *config.Config
-	client      *http.Client
-	limiter     *limiter.DynamicRateLimiter
-	wg          *sync.WaitGroup
-	done        chan struct{}
+	orderRepo      repositories.OrderRepository
+	accountRepo    repositories.AccountRepository
+	deadLetterRepo repositories.DeadLetterRepository
+	listener       repositories.OrderListener
+	trm            *manager.Manager
+	logger         logger.Logger
+	config         *config.Config
+	client         interfaces.AccrualClient
+	limiter        *limiter.DynamicRateLimiter
+	breaker        *breaker.Breaker
+	backoff        backoff.Policy
+	owner          string
+	wg             *sync.WaitGroup
+	stopOnce       sync.Once
+	done           chan struct{}
 }
 
+// NewAccrualService creates the service. Listener is optional,
+// without it orders are only claimed on each tick.
 func NewAccrualService(
 	orderRepo repositories.OrderRepository,
 	accountRepo repositories.AccountRepository,
+	deadLetterRepo repositories.DeadLetterRepository,
+	listener repositories.OrderListener,
+	client interfaces.AccrualClient,
 	trm *manager.Manager,
 	config *config.Config,
 	logger logger.Logger,
@@ -44,47 +54,107 @@ func NewAccrualService(
 	if config == nil {
 		return nil, errors.New("nil dependency: config")
 	}
+	if client == nil {
+		return nil, errors.New("nil dependency: accrual client")
+	}
+	if deadLetterRepo == nil {
+		return nil, errors.New("nil dependency: dead letter repository")
+	}
 	if trm == nil {
 		return nil, errors.New("nil dependency: transaction manager")
 	}
+	if config.Accrual.Burst < 1 {
+		return nil, errors.New("accrual burst must be positive")
+	}
 
-	jar, err := cookiejar.New(nil)
-	if err != nil {
-		return nil, fmt.Errorf("create new cookie jar: %w", err)
+	limiter := limiter.NewDynamicRateLimiter(config.Accrual.Every, config.Accrual.Burst)
+
+	backoff := backoff.Policy{
+		Base:   config.Accrual.Backoff.Base,
+		Factor: config.Accrual.Backoff.Factor,
+		Cap:    config.Accrual.Backoff.Cap,
+		Jitter: config.Accrual.Backoff.Jitter,
 	}
 
-	client := &http.Client{
-		Jar:     jar,
-		Timeout: config.Accrual.Timeout,
+	// Name of the instance leasing orders.
+	owner := config.Accrual.InstanceID
+	if owner == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("get hostname: %w", err)
+		}
+		owner = fmt.Sprintf("%s:%d", hostname, os.Getpid())
 	}
 
-	limiter := limiter.NewDynamicRateLimiter(config.Accrual.Every, config.Accrual.Burst)
+	breaker := breaker.New(breaker.Settings{
+		FailureThreshold: config.Accrual.Breaker.FailureThreshold,
+		OpenTimeout:      config.Accrual.Breaker.OpenTimeout,
+		SuccessThreshold: config.Accrual.Breaker.SuccessThreshold,
+		OnStateChange: func(from, to breaker.State) {
+			logger.Infof("accrual circuit breaker: %s -> %s", from, to)
+		},
+	})
 
 	return &AccrualService{
-		orderRepo:   orderRepo,
-		accountRepo: accountRepo,
-		trm:         trm,
-		logger:      logger,
-		config:      config,
-		client:      client,
-		limiter:     limiter,
-		wg:          &sync.WaitGroup{},
-		done:        make(chan struct{}),
+		orderRepo:      orderRepo,
+		accountRepo:    accountRepo,
+		deadLetterRepo: deadLetterRepo,
+		listener:       listener,
+		trm:            trm,
+		logger:         logger,
+		config:         config,
+		client:         client,
+		limiter:        limiter,
+		breaker:        breaker,
+		backoff:        backoff,
+		owner:          owner,
+		wg:             &sync.WaitGroup{},
+		done:           make(chan struct{}),
 	}, nil
 }
 
+var _ interfaces.AccrualService = (*AccrualService)(nil)
+
+// CircuitState returns the state of the circuit breaker around the accrual client.
+func (s *AccrualService) CircuitState() breaker.State {
+	return s.breaker.State()
+}
+
+// Run starts providing unprocessed orders from the database
+// and the pool of workers handling them.
 func (s *AccrualService) Run(ctx context.Context) {
-	s.wg.Add(1)
+	// Waiting is interrupted on stop, in-flight calls are not.
+	waitCtx, cancel := context.WithCancel(ctx)
 	go func() {
-		defer s.wg.Done()
-		s.run(ctx)
+		defer cancel()
+		select {
+		case <-s.done:
+		case <-ctx.Done():
+		}
 	}()
+
+	// Created orders are handled immediately, the ticker is a safety net.
+	var created <-chan entities.OrderNumber
+	if s.listener != nil {
+		created = s.listener.ListenCreatedOrders(waitCtx)
+	}
+
+	ordersChan := s.provideOrdersFromDB(ctx, created)
+
+	for i := 0; i < s.config.Accrual.Burst; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.work(ctx, waitCtx, ordersChan)
+		}()
+	}
 }
 
+// Stop shuts the pool down and waits for in-flight calls to finish.
 func (s *AccrualService) Stop() {
-	sync.OnceFunc(func() {
+	s.stopOnce.Do(func() {
 		close(s.done)
-	})()
+	})
 
 	ready := make(chan struct{})
 	go func() {
@@ -100,9 +170,9 @@ func (s *AccrualService) Stop() {
 	}
 }
 
-func (s *AccrualService) run(ctx context.Context) {
-	ordersChan := s.provideOrdersFromDB(ctx)
-
+func (s *AccrualService) work(
+	ctx, waitCtx context.Context, ordersChan <-chan *entities.Order,
+) {
 	for {
 		select {
 		case <-s.done:
@@ -112,50 +182,174 @@ func (s *AccrualService) run(ctx context.Context) {
 				return
 			}
 
-			if err := s.limiter.Wait(ctx); err != nil {
-				if !errors.Is(err, context.Canceled) {
-					s.logger.Errorf("wait limiter: %v", err)
+			err := s.process(ctx, waitCtx, order)
+
+			// Interrupted order or the one not checked while the circuit
+			// is open is just given back to be claimed again.
+			if errors.Is(err, context.Canceled) || errors.Is(err, breaker.ErrOpen) {
+				err = s.orderRepo.ReleaseOrder(context.WithoutCancel(ctx), order.Number, s.owner)
+				if err != nil {
+					s.logger.Errorf("release order %s: %v", order.Number, err)
 				}
+				continue
 			}
 
-			if err := s.update(ctx, order.Number); err != nil {
-				if errors.Is(err, errs.ErrRateLimit) {
-					time.Sleep(time.Minute)
-					s.limiter.Update(s.config.Accrual.Every+time.Second, s.config.Accrual.Burst)
-					continue
+			// Order isn't kept leased while the accrual service asks to wait,
+			// it's given back to be checked once the pause is over.
+			var rateLimitErr *errs.RateLimitError
+			if errors.As(err, &rateLimitErr) {
+				err = s.orderRepo.DeferOrder(ctx, order.Number, s.owner, rateLimitErr.RetryAfter)
+				if err != nil {
+					s.logger.Errorf("defer order %s: %v", order.Number, err)
+				}
+				continue
+			}
+
+			// Response that can't be understood is kept for investigation.
+			var malformedErr *errs.MalformedResponseError
+			if errors.As(err, &malformedErr) {
+				s.logger.Errorf("process order %s: %v", order.Number, err)
+				if err = s.deadLetter(ctx, order, malformedErr); err != nil {
+					s.logger.Errorf("dead letter order %s: %v", order.Number, err)
 				}
+				continue
 			}
+
+			// Check unresolved order later, backing off with each attempt.
+			var lastErr string
+			if err != nil {
+				s.logger.Errorf("process order %s: %v", order.Number, err)
+				lastErr = err.Error()
+			}
+
+			delay := s.backoff.Delay(order.Attempts)
+
+			if err = s.orderRepo.RescheduleOrder(ctx, order.Number, s.owner, delay, lastErr); err != nil {
+				s.logger.Errorf("reschedule order %s: %v", order.Number, err)
+			}
+		}
+	}
+}
+
+// process updates the order. It returns the rate limit error without
+// calling the accrual service while the workers are paused, so the order
+// lease is never held through the wait.
+func (s *AccrualService) process(
+	ctx, waitCtx context.Context, order *entities.Order,
+) error {
+	if pause := s.limiter.Paused(); pause > 0 {
+		return &errs.RateLimitError{RetryAfter: pause}
+	}
+
+	// The limiter is shared by all the workers.
+	if err := s.limiter.Wait(waitCtx); err != nil {
+		if !errors.Is(err, context.Canceled) {
+			s.logger.Errorf("wait limiter: %v", err)
+		}
+		return err
+	}
+
+	err := s.update(ctx, order.Number)
+
+	var rateLimitErr *errs.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		if err == nil {
+			s.limiter.Recover()
 		}
+		return err
 	}
+
+	// Pause all the workers as asked and slow down to the advertised rate.
+	s.logger.Infof("accrual service: %s", rateLimitErr)
+	s.limiter.Throttle(rateLimitErr.RetryAfter, rateLimitErr.Interval())
+
+	return rateLimitErr
 }
 
-func (s *AccrualService) provideOrdersFromDB(ctx context.Context) chan *entities.Order {
-	ticker := time.NewTicker(s.config.Accrual.Every)
+// provideOrdersFromDB claims unprocessed orders on each tick
+// and created orders as soon as they are received.
+func (s *AccrualService) provideOrdersFromDB(
+	ctx context.Context, created <-chan entities.OrderNumber,
+) <-chan *entities.Order {
+	// Orders are only reconciled from time to time if updates are pushed.
+	every := s.config.Accrual.Every
+	if s.config.Accrual.WebhookSecret != "" {
+		every = s.config.Accrual.ReconcileEvery
+	}
+
+	ticker := time.NewTicker(every)
 	out := make(chan *entities.Order)
-	offset := 0
+	cursor := 0
+
+	// send returns false if the service is stopped.
+	send := func(order *entities.Order) bool {
+		select {
+		case <-s.done:
+			return false
+		case <-ctx.Done():
+			return false
+		case out <- order:
+			return true
+		}
+	}
 
+	s.wg.Add(1)
 	go func() {
+		defer s.wg.Done()
+		defer ticker.Stop()
 		defer close(out)
 
 		for {
 			select {
+			case <-s.done:
+				return
 			case <-ctx.Done():
 				return
+			case num, open := <-created:
+				if !open {
+					created = nil
+					continue
+				}
+
+				// Created order is claimed on the tick after the circuit closes.
+				if s.breaker.State() == breaker.Open {
+					continue
+				}
+
+				order, err := s.orderRepo.ClaimOrder(ctx, s.owner, s.config.Accrual.Lease, num)
+				if err != nil {
+					if !errors.Is(err, errs.ErrNotFound) {
+						s.logger.Errorf("claim created order %s: %v", num, err)
+					}
+					continue
+				}
+
+				if !send(order) {
+					return
+				}
 			case <-ticker.C:
-				orders, err := s.orderRepo.GetUnprocessedOrders(ctx, s.config.Accrual.Limit, offset)
+				// Don't hand out orders while the accrual service is down.
+				if s.breaker.State() == breaker.Open {
+					continue
+				}
+
+				orders, next, err := s.orderRepo.ClaimUnprocessedOrders(ctx,
+					s.owner, s.config.Accrual.Lease, cursor, s.config.Accrual.Limit)
 				if err != nil {
 					if errors.Is(err, errs.ErrNotFound) {
-						offset = 0
+						cursor = 0
 						continue
 					}
-					s.logger.Errorf("get unprocessed orders: %v", err)
+					s.logger.Errorf("claim unprocessed orders: %v", err)
 					continue
 				}
 
-				offset += len(orders)
+				cursor = next
 
 				for _, order := range orders {
-					out <- order
+					if !send(order) {
+						return
+					}
 				}
 			}
 		}
@@ -165,7 +359,12 @@ func (s *AccrualService) provideOrdersFromDB(ctx context.Context) chan *entities
 }
 
 func (s *AccrualService) update(ctx context.Context, num entities.OrderNumber) error {
-	info, err := s.get(ctx, num)
+	if err := s.breaker.Allow(); err != nil {
+		return err
+	}
+
+	info, err := s.client.GetOrderInfo(ctx, num)
+	s.breaker.Done(!isAccrualFailure(err))
 	if err != nil {
 		// [http.StatusNoContent]
 		if errors.Is(err, errs.ErrNotFound) {
@@ -174,50 +373,128 @@ func (s *AccrualService) update(ctx context.Context, num entities.OrderNumber) e
 		return fmt.Errorf("get order info: %w", err)
 	}
 
+	// The order is cancelled while it was checked.
+	if err = s.ApplyUpdate(ctx, info); errors.Is(err, errs.ErrNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+// deadLetter saves the malformed response and parks
+// the order until the dead letter is replayed.
+func (s *AccrualService) deadLetter(
+	ctx context.Context, order *entities.Order, malformedErr *errs.MalformedResponseError,
+) error {
 	return s.trm.Do(ctx, func(ctx context.Context) error {
-		var userID user.ID
+		letter := &entities.DeadLetter{
+			Order:      order.Number,
+			StatusCode: malformedErr.StatusCode,
+			Body:       malformedErr.Body,
+			Error:      malformedErr.Err.Error(),
+		}
+
+		if err := s.deadLetterRepo.SaveDeadLetter(ctx, letter); err != nil {
+			return fmt.Errorf("save dead letter: %w", err)
+		}
+
+		if err := s.orderRepo.ParkOrder(ctx, order.Number, s.owner, malformedErr.Error()); err != nil {
+			return fmt.Errorf("park order: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetDeadLetters returns malformed responses of the accrual system.
+func (s *AccrualService) GetDeadLetters(ctx context.Context) ([]*entities.DeadLetter, error) {
+	return s.deadLetterRepo.GetDeadLetters(ctx)
+}
 
-		userID, err = s.orderRepo.UpdateOrder(ctx, info)
+// ReplayDeadLetter drops the dead letter and checks its order again.
+func (s *AccrualService) ReplayDeadLetter(ctx context.Context, id int) error {
+	return s.trm.Do(ctx, func(ctx context.Context) error {
+		letter, err := s.deadLetterRepo.GetDeadLetter(ctx, id)
 		if err != nil {
-			return fmt.Errorf("update order: %w", err)
+			return fmt.Errorf("get dead letter: %w", err)
 		}
 
-		if info.Accrual.GreaterThan(decimal.NewFromInt(0)) {
-			if err = s.accountRepo.AddToAccount(ctx, userID, info.Accrual); err != nil {
-				return fmt.Errorf("add to account: %w", err)
-			}
+		if err = s.orderRepo.UnparkOrder(ctx, letter.Order); err != nil {
+			return fmt.Errorf("unpark order: %w", err)
+		}
+
+		if err = s.deadLetterRepo.DeleteDeadLetter(ctx, id); err != nil {
+			return fmt.Errorf("delete dead letter: %w", err)
 		}
 
 		return nil
 	})
 }
 
-func (s *AccrualService) get(ctx context.Context, num entities.OrderNumber) (*entities.UpdateOrderInfo, error) {
-	url := fmt.Sprintf("%s/api/orders/%s", s.config.Accrual.Address, num)
+// DiscardDeadLetter drops the dead letter leaving its order parked.
+// Parked order is eventually expired.
+func (s *AccrualService) DiscardDeadLetter(ctx context.Context, id int) error {
+	return s.deadLetterRepo.DeleteDeadLetter(ctx, id)
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
-	if err != nil {
-		return nil, fmt.Errorf("new request: %w", err)
-	}
+// isAccrualFailure reports whether the error means that the accrual
+// service is down. Unregistered orders and rate limits are normal answers,
+// malformed responses are answers too.
+func isAccrualFailure(err error) bool {
+	return err != nil &&
+		!errors.Is(err, errs.ErrNotFound) &&
+		!errors.Is(err, errs.ErrRateLimit) &&
+		!errors.Is(err, errs.ErrMalformedResponse) &&
+		!errors.Is(err, context.Canceled)
+}
 
-	res, err := s.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("do request: %w", err)
-	}
-	defer res.Body.Close()
+// ApplyUpdate moves the order to the status from the accrual system
+// and credits the accrual in the same transaction on the transition
+// into PROCESSED. Updates not changing the status are ignored.
+func (s *AccrualService) ApplyUpdate(ctx context.Context, info *entities.UpdateOrderInfo) error {
+	return s.trm.Do(ctx, func(ctx context.Context) error {
+		order, err := s.orderRepo.LockOrder(ctx, info.Number)
+		if err != nil {
+			return fmt.Errorf("lock order: %w", err)
+		}
 
-	switch res.StatusCode {
-	case http.StatusTooManyRequests:
-		return nil, errs.ErrRateLimit
-	case http.StatusNoContent:
-		return nil, errs.ErrNotFound
-	default:
-		payload := new(accrual.UpdateOrderInfo)
+		if order.Status == info.Status {
+			return nil
+		}
 
-		if err = json.NewDecoder(res.Body).Decode(payload); err != nil {
-			return nil, fmt.Errorf("decode response: %w", err)
+		if err = order.Transition(info.Status); err != nil {
+			return err
 		}
 
-		return entities.NewUpdateInfoFromResponse(payload), nil
-	}
+		userID, err := s.orderRepo.UpdateOrder(ctx, info)
+		if err != nil {
+			return fmt.Errorf("update order: %w", err)
+		}
+
+		if err = s.orderRepo.SaveStatusChange(ctx, order.ID, info.Status, info.Accrual); err != nil {
+			return fmt.Errorf("save status change: %w", err)
+		}
+
+		if info.Status != entities.PROCESSED ||
+			!info.Accrual.GreaterThan(decimal.NewFromInt(0)) {
+			return nil
+		}
+
+		// Write accrual to the operations history table first:
+		// it guarantees that the order is credited only once.
+		accrualOperation := entities.NewAccrualOperation(userID, info.Number, info.Accrual)
+
+		if err = s.accountRepo.SaveAccountOperation(ctx, accrualOperation); err != nil {
+			if errors.Is(err, errs.ErrAlreadyExists) {
+				return nil
+			}
+			return fmt.Errorf("save account operation: %w", err)
+		}
+
+		if err = s.accountRepo.AddToAccount(ctx, userID, info.Accrual); err != nil {
+			return fmt.Errorf("add to account: %w", err)
+		}
+
+		return nil
+	})
 }
@@ -0,0 +1,763 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/config"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
+	"github.com/KretovDmitry/gophermart/internal/domain/repositories"
+	"github.com/KretovDmitry/gophermart/internal/infrastructure/accrual/memory"
+	"github.com/KretovDmitry/gophermart/pkg/breaker"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+	"github.com/avito-tech/go-transaction-manager/trm/v2"
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUserID user.ID = 1
+
+func TestAccrualService(t *testing.T) {
+	tests := []struct {
+		name        string
+		script      []memory.Response
+		wantStatus  entities.OrderStatus
+		wantBalance decimal.Decimal
+		wantCalls   int
+	}{
+		{
+			name:        "processed",
+			script:      []memory.Response{memory.Processed(decimal.NewFromInt(500))},
+			wantStatus:  entities.PROCESSED,
+			wantBalance: decimal.NewFromInt(500),
+			wantCalls:   1,
+		},
+		{
+			name: "registered then processed",
+			script: []memory.Response{
+				memory.Registered(),
+				memory.Processing(),
+				memory.Processed(decimal.RequireFromString("729.98")),
+			},
+			wantStatus:  entities.PROCESSED,
+			wantBalance: decimal.RequireFromString("729.98"),
+			wantCalls:   3,
+		},
+		{
+			name:        "processed without accrual",
+			script:      []memory.Response{memory.Processed(decimal.Zero)},
+			wantStatus:  entities.PROCESSED,
+			wantBalance: decimal.Zero,
+			wantCalls:   1,
+		},
+		{
+			name:        "invalid",
+			script:      []memory.Response{memory.Invalid()},
+			wantStatus:  entities.INVALID,
+			wantBalance: decimal.Zero,
+			wantCalls:   1,
+		},
+		{
+			name: "not registered yet",
+			script: []memory.Response{
+				memory.NotRegistered(),
+				memory.Processed(decimal.NewFromInt(10)),
+			},
+			wantStatus:  entities.PROCESSED,
+			wantBalance: decimal.NewFromInt(10),
+			wantCalls:   2,
+		},
+		{
+			name: "rate limited",
+			script: []memory.Response{
+				memory.RateLimited(10*time.Millisecond, 6000),
+				memory.Processed(decimal.NewFromInt(10)),
+			},
+			wantStatus:  entities.PROCESSED,
+			wantBalance: decimal.NewFromInt(10),
+			wantCalls:   2,
+		},
+		{
+			name: "failed",
+			script: []memory.Response{
+				memory.Failed(errors.New("connection refused")),
+				memory.Processed(decimal.NewFromInt(10)),
+			},
+			wantStatus:  entities.PROCESSED,
+			wantBalance: decimal.NewFromInt(10),
+			wantCalls:   2,
+		},
+		{
+			name: "slow",
+			script: []memory.Response{
+				memory.Processed(decimal.NewFromInt(10)).WithLatency(20 * time.Millisecond),
+			},
+			wantStatus:  entities.PROCESSED,
+			wantBalance: decimal.NewFromInt(10),
+			wantCalls:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const num entities.OrderNumber = "79927398713"
+
+			env := newAccrualTestEnv(t)
+			env.orders.add(num)
+			env.client.Script(num, tt.script...)
+
+			env.service.Run(context.Background())
+
+			assert.Eventually(t, func() bool {
+				return env.orders.get(num).Status == tt.wantStatus &&
+					env.client.Calls(num) >= tt.wantCalls
+			}, time.Second, time.Millisecond)
+
+			env.service.Stop()
+
+			assert.Equal(t, tt.wantCalls, env.client.Calls(num))
+			assert.True(t, tt.wantBalance.Equal(env.accounts.balance()),
+				"want balance %s, got %s", tt.wantBalance, env.accounts.balance())
+		})
+	}
+}
+
+func TestAccrualServiceCreditsOnce(t *testing.T) {
+	const num entities.OrderNumber = "79927398713"
+
+	env := newAccrualTestEnv(t)
+	env.orders.add(num)
+	env.client.Script(num, memory.Processed(decimal.NewFromInt(500)))
+
+	ctx := context.Background()
+	require.NoError(t, env.service.update(ctx, num))
+	require.NoError(t, env.service.update(ctx, num))
+
+	assert.True(t, decimal.NewFromInt(500).Equal(env.accounts.balance()))
+	assert.Len(t, env.accounts.operations, 1)
+}
+
+func TestAccrualServiceRejectsInvalidTransitions(t *testing.T) {
+	const num entities.OrderNumber = "79927398713"
+
+	env := newAccrualTestEnv(t)
+	env.orders.add(num)
+
+	ctx := context.Background()
+	processed := &entities.UpdateOrderInfo{
+		Number:  num,
+		Status:  entities.PROCESSED,
+		Accrual: decimal.NewFromInt(500),
+	}
+
+	require.NoError(t, env.service.ApplyUpdate(ctx, processed))
+	// Repeated update of the final order is ignored.
+	require.NoError(t, env.service.ApplyUpdate(ctx, processed))
+
+	for _, status := range []entities.OrderStatus{entities.PROCESSING, entities.INVALID} {
+		err := env.service.ApplyUpdate(ctx, &entities.UpdateOrderInfo{Number: num, Status: status})
+		assert.ErrorIs(t, err, errs.ErrInvalidTransition)
+	}
+
+	assert.Equal(t, entities.PROCESSED, env.orders.get(num).Status)
+	assert.True(t, decimal.NewFromInt(500).Equal(env.accounts.balance()))
+	assert.Len(t, env.accounts.operations, 1)
+}
+
+func TestAccrualServiceRecordsHistory(t *testing.T) {
+	const num entities.OrderNumber = "79927398713"
+
+	env := newAccrualTestEnv(t)
+	env.orders.add(num)
+	env.client.Script(num,
+		memory.Registered(),
+		memory.Processing(),
+		memory.Processed(decimal.NewFromInt(500)),
+	)
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, env.service.update(ctx, num))
+	}
+
+	history := env.orders.history(num)
+
+	// REGISTERED and PROCESSING are both PROCESSING for the user.
+	statuses := make([]entities.OrderStatus, len(history))
+	for i, change := range history {
+		statuses[i] = change.Status
+	}
+	assert.Equal(t, []entities.OrderStatus{entities.PROCESSING, entities.PROCESSED}, statuses)
+	assert.True(t, decimal.NewFromInt(500).Equal(history[1].Accrual))
+}
+
+func TestAccrualServiceDeadLetters(t *testing.T) {
+	const num entities.OrderNumber = "79927398713"
+
+	env := newAccrualTestEnv(t)
+	env.orders.add(num)
+	env.client.Script(num,
+		memory.Malformed(http.StatusOK, "<html>"),
+		memory.Processed(decimal.NewFromInt(500)),
+	)
+
+	env.service.Run(context.Background())
+	defer env.service.Stop()
+
+	ctx := context.Background()
+
+	var letters []*entities.DeadLetter
+	require.Eventually(t, func() bool {
+		letters, _ = env.service.GetDeadLetters(ctx)
+		return len(letters) == 1
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, num, letters[0].Order)
+	assert.Equal(t, "<html>", letters[0].Body)
+
+	// Parked order is not checked until the dead letter is replayed.
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 1, env.client.Calls(num))
+	assert.Equal(t, entities.NEW, env.orders.get(num).Status)
+
+	require.NoError(t, env.service.ReplayDeadLetter(ctx, letters[0].ID))
+
+	assert.Eventually(t, func() bool {
+		return env.orders.get(num).Status == entities.PROCESSED
+	}, time.Second, time.Millisecond)
+
+	_, err := env.service.GetDeadLetters(ctx)
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+	assert.ErrorIs(t, env.service.DiscardDeadLetter(ctx, letters[0].ID), errs.ErrNotFound)
+}
+
+func TestAccrualServiceStopWaitsInFlight(t *testing.T) {
+	const num entities.OrderNumber = "79927398713"
+
+	env := newAccrualTestEnv(t)
+	env.orders.add(num)
+	env.client.Script(num, memory.Processed(decimal.NewFromInt(1)).WithLatency(50*time.Millisecond))
+
+	env.service.Run(context.Background())
+
+	require.Eventually(t, func() bool {
+		return env.client.Calls(num) > 0
+	}, time.Second, time.Millisecond)
+
+	env.service.Stop()
+
+	assert.Equal(t, entities.PROCESSED, env.orders.get(num).Status)
+}
+
+func TestAccrualServiceHandlesCreatedOrders(t *testing.T) {
+	const num entities.OrderNumber = "79927398713"
+
+	env := newAccrualTestEnv(t)
+	env.service.config.Accrual.Every = time.Hour
+	env.orders.add(num)
+	env.client.Script(num, memory.Processed(decimal.NewFromInt(1)))
+
+	env.service.Run(context.Background())
+	defer env.service.Stop()
+
+	env.created <- num
+
+	assert.Eventually(t, func() bool {
+		return env.orders.get(num).Status == entities.PROCESSED
+	}, time.Second, time.Millisecond)
+}
+
+func TestAccrualServiceOpensCircuit(t *testing.T) {
+	const num entities.OrderNumber = "79927398713"
+
+	env := newAccrualTestEnv(t)
+	env.orders.add(num)
+	env.client.Script(num, memory.Failed(errors.New("connection refused")))
+
+	env.service.Run(context.Background())
+
+	require.Eventually(t, func() bool {
+		return env.service.CircuitState() == breaker.Open
+	}, time.Second, time.Millisecond)
+
+	// No more orders are handed out while the circuit is open.
+	time.Sleep(20 * time.Millisecond)
+	env.service.Stop()
+
+	assert.Equal(t, 2, env.client.Calls(num))
+	assert.Equal(t, "get order info: connection refused", env.orders.lastErr(num))
+}
+
+func TestAccrualServiceReleasesOrdersWhileCircuitIsOpen(t *testing.T) {
+	const num entities.OrderNumber = "79927398713"
+
+	env := newAccrualTestEnv(t)
+	env.orders.add(num)
+	env.client.Script(num, memory.Processed(decimal.NewFromInt(10)))
+
+	// The order is claimed just before the circuit opens.
+	order, err := env.orders.ClaimOrder(context.Background(), "test", time.Minute, num)
+	require.NoError(t, err)
+
+	for i := 0; i < env.service.config.Accrual.Breaker.FailureThreshold; i++ {
+		require.NoError(t, env.service.breaker.Allow())
+		env.service.breaker.Done(false)
+	}
+	require.Equal(t, breaker.Open, env.service.CircuitState())
+
+	orders := make(chan *entities.Order, 1)
+	orders <- order
+	close(orders)
+
+	env.service.work(context.Background(), context.Background(), orders)
+
+	// Released, not rescheduled: the lease is cleared and the attempt isn't counted.
+	owner, nextCheck := env.orders.lease(num)
+	assert.Empty(t, owner)
+	assert.True(t, nextCheck.IsZero(), "order is not postponed")
+	assert.Zero(t, env.orders.get(num).Attempts)
+	assert.Empty(t, env.orders.lastErr(num))
+	assert.Zero(t, env.client.Calls(num))
+}
+
+func TestAccrualServiceDefersRateLimitedOrders(t *testing.T) {
+	const (
+		num   entities.OrderNumber = "79927398713"
+		other entities.OrderNumber = "4561261212345467"
+	)
+
+	env := newAccrualTestEnv(t)
+	env.orders.add(num)
+	env.client.Script(num, memory.RateLimited(time.Hour, 0))
+
+	env.service.Run(context.Background())
+
+	// The lease is given back right away instead of being held for an hour.
+	require.Eventually(t, func() bool {
+		owner, nextCheck := env.orders.lease(num)
+		return env.client.Calls(num) == 1 && owner == "" &&
+			nextCheck.After(time.Now().Add(59*time.Minute))
+	}, time.Second, time.Millisecond)
+
+	// Orders claimed during the pause are deferred without a call.
+	env.orders.add(other)
+	require.Eventually(t, func() bool {
+		owner, nextCheck := env.orders.lease(other)
+		return owner == "" && nextCheck.After(time.Now().Add(59*time.Minute))
+	}, time.Second, time.Millisecond)
+
+	env.service.Stop()
+
+	assert.Zero(t, env.client.Calls(other))
+	assert.Zero(t, env.orders.get(num).Attempts)
+}
+
+type accrualTestEnv struct {
+	service  *AccrualService
+	client   *memory.Client
+	orders   *stubAccrualOrderRepository
+	accounts *stubAccrualAccountRepository
+	letters  *stubDeadLetterRepository
+	created  chan entities.OrderNumber
+}
+
+func newAccrualTestEnv(t *testing.T) *accrualTestEnv {
+	t.Helper()
+
+	cfg := &config.Config{
+		Accrual: config.Accrual{
+			Limit:      10,
+			Every:      time.Millisecond,
+			Burst:      2,
+			InstanceID: "test",
+			Lease:      time.Minute,
+			Backoff: config.Backoff{
+				Base:   time.Millisecond,
+				Factor: 1,
+				Cap:    time.Millisecond,
+			},
+			Breaker: config.Breaker{
+				FailureThreshold: 2,
+				OpenTimeout:      time.Hour,
+				SuccessThreshold: 1,
+			},
+		},
+		HTTPServer: config.HTTPServer{ShutdownTimeout: time.Second},
+	}
+
+	env := &accrualTestEnv{
+		client:   memory.NewClient(),
+		orders:   newStubAccrualOrderRepository(),
+		accounts: &stubAccrualAccountRepository{},
+		letters:  &stubDeadLetterRepository{},
+		created:  make(chan entities.OrderNumber),
+	}
+
+	logger, _ := logger.NewForTest()
+
+	var err error
+	env.service, err = NewAccrualService(env.orders, env.accounts, env.letters, stubOrderListener(env.created),
+		env.client, manager.Must(stubTrFactory), cfg, logger)
+	require.NoError(t, err)
+
+	return env
+}
+
+type stubOrderListener chan entities.OrderNumber
+
+func (l stubOrderListener) ListenCreatedOrders(context.Context) <-chan entities.OrderNumber {
+	return l
+}
+
+func stubTrFactory(ctx context.Context, _ trm.Settings) (context.Context, trm.Transaction, error) {
+	return ctx, stubTransaction{}, nil
+}
+
+type stubTransaction struct{}
+
+func (stubTransaction) Transaction() interface{}       { return nil }
+func (stubTransaction) Commit(context.Context) error   { return nil }
+func (stubTransaction) Rollback(context.Context) error { return nil }
+func (stubTransaction) IsActive() bool                 { return true }
+
+type stubOrder struct {
+	entities.Order
+	owner     string
+	nextCheck time.Time
+	lastErr   string
+	parked    bool
+	history   []*entities.OrderStatusChange
+}
+
+// stubAccrualOrderRepository keeps the orders and their leases
+// for the worker. Orders are claimed by the single owner in one batch.
+type stubAccrualOrderRepository struct {
+	repositories.OrderRepository
+
+	mu     sync.Mutex
+	orders map[entities.OrderNumber]*stubOrder
+}
+
+func newStubAccrualOrderRepository() *stubAccrualOrderRepository {
+	return &stubAccrualOrderRepository{orders: make(map[entities.OrderNumber]*stubOrder)}
+}
+
+func (r *stubAccrualOrderRepository) add(num entities.OrderNumber) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order := entities.NewOrder(testUserID, num)
+	order.ID = len(r.orders) + 1
+	r.orders[num] = &stubOrder{Order: *order}
+}
+
+func (r *stubAccrualOrderRepository) get(num entities.OrderNumber) entities.Order {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.orders[num].Order
+}
+
+func (r *stubAccrualOrderRepository) lease(num entities.OrderNumber) (string, time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.orders[num].owner, r.orders[num].nextCheck
+}
+
+func (r *stubAccrualOrderRepository) lastErr(num entities.OrderNumber) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.orders[num].lastErr
+}
+
+func (r *stubAccrualOrderRepository) history(num entities.OrderNumber) []*entities.OrderStatusChange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.orders[num].history)
+}
+
+// claimable reports if the order is handed out to the worker.
+func (o *stubOrder) claimable() bool {
+	return o.owner == "" && !o.parked && !o.nextCheck.After(time.Now()) && !o.Status.IsFinal()
+}
+
+func (r *stubAccrualOrderRepository) ClaimUnprocessedOrders(
+	_ context.Context, owner string, _ time.Duration, _, limit int,
+) ([]*entities.Order, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	orders := make([]*entities.Order, 0, limit)
+	for _, o := range r.orders {
+		if o.claimable() && len(orders) < limit {
+			o.owner = owner
+			order := o.Order
+			orders = append(orders, &order)
+		}
+	}
+
+	if len(orders) == 0 {
+		return nil, 0, errs.ErrNotFound
+	}
+
+	return orders, 0, nil
+}
+
+func (r *stubAccrualOrderRepository) ClaimOrder(
+	_ context.Context, owner string, _ time.Duration, num entities.OrderNumber,
+) (*entities.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, ok := r.orders[num]
+	if !ok || o.owner != "" || o.parked || o.Status.IsFinal() {
+		return nil, errs.ErrNotFound
+	}
+	o.owner = owner
+	order := o.Order
+
+	return &order, nil
+}
+
+func (r *stubAccrualOrderRepository) RescheduleOrder(
+	_ context.Context, num entities.OrderNumber, owner string, delay time.Duration, lastErr string,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o := r.orders[num]
+	if o.owner != owner {
+		return nil
+	}
+	o.owner = ""
+	o.Attempts++
+	o.lastErr = lastErr
+	o.nextCheck = time.Now().Add(delay)
+
+	return nil
+}
+
+func (r *stubAccrualOrderRepository) ReleaseOrder(
+	_ context.Context, num entities.OrderNumber, owner string,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if o := r.orders[num]; o.owner == owner {
+		o.owner = ""
+	}
+
+	return nil
+}
+
+func (r *stubAccrualOrderRepository) DeferOrder(
+	_ context.Context, num entities.OrderNumber, owner string, delay time.Duration,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o := r.orders[num]
+	if o.owner != owner {
+		return nil
+	}
+	o.owner = ""
+	o.nextCheck = time.Now().Add(delay)
+
+	return nil
+}
+
+func (r *stubAccrualOrderRepository) ParkOrder(
+	_ context.Context, num entities.OrderNumber, owner string, lastErr string,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o := r.orders[num]
+	if o.owner != owner {
+		return nil
+	}
+	o.owner = ""
+	o.Attempts++
+	o.lastErr = lastErr
+	o.parked = true
+
+	return nil
+}
+
+func (r *stubAccrualOrderRepository) UnparkOrder(_ context.Context, num entities.OrderNumber) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if o := r.orders[num]; o.parked {
+		o.parked = false
+		o.Attempts = 0
+		o.lastErr = ""
+		o.nextCheck = time.Time{}
+	}
+
+	return nil
+}
+
+func (r *stubAccrualOrderRepository) LockOrder(
+	_ context.Context, num entities.OrderNumber,
+) (*entities.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, ok := r.orders[num]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+	order := o.Order
+
+	return &order, nil
+}
+
+func (r *stubAccrualOrderRepository) UpdateOrder(
+	_ context.Context, info *entities.UpdateOrderInfo,
+) (user.ID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, ok := r.orders[info.Number]
+	if !ok {
+		return -1, errs.ErrNotFound
+	}
+	if o.Status.IsFinal() {
+		return -1, errs.ErrInvalidTransition
+	}
+	o.Status = info.Status
+	o.Accrual = info.Accrual
+
+	return o.UserID, nil
+}
+
+func (r *stubAccrualOrderRepository) SaveStatusChange(
+	_ context.Context, orderID int, status entities.OrderStatus, accrual decimal.Decimal,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, o := range r.orders {
+		if o.ID == orderID {
+			o.history = append(o.history, &entities.OrderStatusChange{
+				Status:    status,
+				Accrual:   accrual,
+				ChangedAt: time.Now(),
+			})
+			return nil
+		}
+	}
+
+	return errs.ErrNotFound
+}
+
+// stubAccrualAccountRepository credits the balance and records
+// the operations. The second accrual of the order is refused
+// the same as by the unique index.
+type stubAccrualAccountRepository struct {
+	repositories.AccountRepository
+
+	mu         sync.Mutex
+	sum        decimal.Decimal
+	operations []*entities.Operation
+}
+
+func (r *stubAccrualAccountRepository) balance() decimal.Decimal {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.sum
+}
+
+func (r *stubAccrualAccountRepository) SaveAccountOperation(_ context.Context, op *entities.Operation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, saved := range r.operations {
+		if saved.Type == entities.ACCRUAL && saved.Type == op.Type && saved.Order == op.Order {
+			return fmt.Errorf("%w: accrual for order %q", errs.ErrAlreadyExists, op.Order)
+		}
+	}
+	r.operations = append(r.operations, op)
+
+	return nil
+}
+
+func (r *stubAccrualAccountRepository) AddToAccount(_ context.Context, _ user.ID, sum decimal.Decimal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sum = r.sum.Add(sum)
+
+	return nil
+}
+
+type stubDeadLetterRepository struct {
+	mu      sync.Mutex
+	letters []*entities.DeadLetter
+}
+
+var _ repositories.DeadLetterRepository = (*stubDeadLetterRepository)(nil)
+
+func (r *stubDeadLetterRepository) SaveDeadLetter(_ context.Context, letter *entities.DeadLetter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	letter.ID = len(r.letters) + 1
+	letter.CreatedAt = time.Now()
+	r.letters = append(r.letters, letter)
+
+	return nil
+}
+
+func (r *stubDeadLetterRepository) GetDeadLetters(context.Context) ([]*entities.DeadLetter, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.letters) == 0 {
+		return nil, errs.ErrNotFound
+	}
+
+	return append([]*entities.DeadLetter(nil), r.letters...), nil
+}
+
+func (r *stubDeadLetterRepository) GetDeadLetter(_ context.Context, id int) (*entities.DeadLetter, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, letter := range r.letters {
+		if letter.ID == id {
+			return letter, nil
+		}
+	}
+
+	return nil, errs.ErrNotFound
+}
+
+func (r *stubDeadLetterRepository) DeleteDeadLetter(_ context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, letter := range r.letters {
+		if letter.ID == id {
+			r.letters = append(r.letters[:i], r.letters[i+1:]...)
+			return nil
+		}
+	}
+
+	return errs.ErrNotFound
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/config"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/domain/repositories"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+)
+
+// Metrics of the expiry job published by expvar: runs, errors
+// and the number of expired orders by status.
+var expiryMetrics = expvar.NewMap("order_expiry")
+
+type ExpiryService struct {
+	*job
+	orderRepo repositories.OrderRepository
+	logger    logger.Logger
+	config    *config.Config
+}
+
+// NewExpiryService creates the service invalidating orders
+// the accrual service hasn't resolved for too long.
+func NewExpiryService(
+	orderRepo repositories.OrderRepository,
+	config *config.Config,
+	logger logger.Logger,
+) (*ExpiryService, error) {
+	if orderRepo == nil {
+		return nil, errors.New("nil dependency: order repository")
+	}
+	if config == nil {
+		return nil, errors.New("nil dependency: config")
+	}
+	if config.Expiry.Every <= 0 {
+		return nil, errors.New("expiry interval must be positive")
+	}
+
+	s := &ExpiryService{
+		orderRepo: orderRepo,
+		logger:    logger,
+		config:    config,
+	}
+	s.job = newJob(config.Expiry.Every, s.expire)
+
+	return s, nil
+}
+
+func (s *ExpiryService) expire(ctx context.Context) {
+	expiryMetrics.Add("runs", 1)
+
+	maxAges := []struct {
+		status entities.OrderStatus
+		maxAge time.Duration
+	}{
+		{entities.NEW, s.config.Expiry.NewMaxAge},
+		{entities.PROCESSING, s.config.Expiry.ProcessingMaxAge},
+	}
+
+	for _, m := range maxAges {
+		if m.maxAge <= 0 {
+			continue
+		}
+
+		reason := fmt.Sprintf("not resolved by the accrual system: %s for more than %s", m.status, m.maxAge)
+
+		n, err := s.orderRepo.ExpireOrders(ctx, m.status, m.maxAge, reason)
+		if err != nil {
+			expiryMetrics.Add("errors", 1)
+			s.logger.Errorf("expire %s orders: %v", m.status, err)
+			continue
+		}
+
+		if n > 0 {
+			expiryMetrics.Add("expired_"+string(m.status), int64(n))
+			s.logger.Infof("expired %d %s orders", n, m.status)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/config"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/domain/repositories"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiryService(t *testing.T) {
+	orders := &stubExpiryOrderRepository{
+		expired: map[entities.OrderStatus]int{entities.NEW: 2, entities.PROCESSING: 1},
+	}
+
+	cfg := &config.Config{
+		Expiry: config.Expiry{
+			Every:            time.Millisecond,
+			NewMaxAge:        time.Hour,
+			ProcessingMaxAge: 2 * time.Hour,
+		},
+	}
+
+	logger, _ := logger.NewForTest()
+
+	expired := func(status entities.OrderStatus) int64 {
+		if v, ok := expiryMetrics.Get("expired_" + string(status)).(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	beforeNew, beforeProcessing := expired(entities.NEW), expired(entities.PROCESSING)
+
+	service, err := NewExpiryService(orders, cfg, logger)
+	require.NoError(t, err)
+
+	service.Run(context.Background())
+
+	require.Eventually(t, func() bool {
+		return len(orders.getCalls()) >= 2
+	}, time.Second, time.Millisecond)
+
+	service.Stop()
+
+	// Each status is expired with its own max age.
+	calls := orders.getCalls()
+	assert.Equal(t, expireCall{
+		status: entities.NEW,
+		maxAge: time.Hour,
+		reason: "not resolved by the accrual system: NEW for more than 1h0m0s",
+	}, calls[0])
+	assert.Equal(t, expireCall{
+		status: entities.PROCESSING,
+		maxAge: 2 * time.Hour,
+		reason: "not resolved by the accrual system: PROCESSING for more than 2h0m0s",
+	}, calls[1])
+
+	runs := int64(len(calls) / 2)
+	assert.Equal(t, beforeNew+2*runs, expired(entities.NEW))
+	assert.Equal(t, beforeProcessing+runs, expired(entities.PROCESSING))
+}
+
+type expireCall struct {
+	status entities.OrderStatus
+	maxAge time.Duration
+	reason string
+}
+
+// stubExpiryOrderRepository records the expiry requests
+// and reports the given number of orders expired by each.
+type stubExpiryOrderRepository struct {
+	repositories.OrderRepository
+
+	mu      sync.Mutex
+	expired map[entities.OrderStatus]int
+	calls   []expireCall
+}
+
+func (r *stubExpiryOrderRepository) ExpireOrders(
+	_ context.Context, status entities.OrderStatus, maxAge time.Duration, reason string,
+) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, expireCall{status: status, maxAge: maxAge, reason: reason})
+
+	return r.expired[status], nil
+}
+
+func (r *stubExpiryOrderRepository) getCalls() []expireCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]expireCall(nil), r.calls...)
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"expvar"
+
+	"github.com/KretovDmitry/gophermart/internal/application/interfaces"
+	"github.com/KretovDmitry/gophermart/internal/config"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+)
+
+// Metrics of the hold expiry job published by expvar:
+// runs, errors and the number of released holds.
+var holdExpiryMetrics = expvar.NewMap("hold_expiry")
+
+type HoldExpiryService struct {
+	*job
+	accountService interfaces.AccountService
+	logger         logger.Logger
+}
+
+// NewHoldExpiryService creates the service returning
+// the funds of expired holds to the balance.
+func NewHoldExpiryService(
+	accountService interfaces.AccountService,
+	config *config.Config,
+	logger logger.Logger,
+) (*HoldExpiryService, error) {
+	if accountService == nil {
+		return nil, errors.New("nil dependency: account service")
+	}
+	if config == nil {
+		return nil, errors.New("nil dependency: config")
+	}
+	if config.Holds.ReleaseEvery <= 0 {
+		return nil, errors.New("hold release interval must be positive")
+	}
+
+	s := &HoldExpiryService{accountService: accountService, logger: logger}
+	s.job = newJob(config.Holds.ReleaseEvery, s.release)
+
+	return s, nil
+}
+
+func (s *HoldExpiryService) release(ctx context.Context) {
+	holdExpiryMetrics.Add("runs", 1)
+
+	// Holds released before the error are counted too.
+	n, err := s.accountService.ReleaseExpiredHolds(ctx)
+	if n > 0 {
+		holdExpiryMetrics.Add("released", int64(n))
+		s.logger.Infof("released %d expired holds", n)
+	}
+	if err != nil {
+		holdExpiryMetrics.Add("errors", 1)
+		s.logger.Errorf("release expired holds: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/application/interfaces"
+	"github.com/KretovDmitry/gophermart/internal/config"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHoldExpiryService(t *testing.T) {
+	accounts := &stubHoldExpiryAccountService{released: 2, err: errors.New("boom")}
+
+	cfg := &config.Config{
+		Holds: config.Holds{ReleaseEvery: time.Millisecond},
+	}
+
+	logger, _ := logger.NewForTest()
+
+	metric := func(key string) int64 {
+		if v, ok := holdExpiryMetrics.Get(key).(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	released, errs := metric("released"), metric("errors")
+
+	service, err := NewHoldExpiryService(accounts, cfg, logger)
+	require.NoError(t, err)
+
+	service.Run(context.Background())
+
+	require.Eventually(t, func() bool {
+		return accounts.calls.Load() > 1
+	}, time.Second, time.Millisecond, "the job keeps running after an error")
+
+	service.Stop()
+
+	calls := accounts.calls.Load()
+	assert.Equal(t, released+2*calls, metric("released"), "holds released before an error are counted")
+	assert.Equal(t, errs+calls, metric("errors"))
+}
+
+// stubHoldExpiryAccountService reports the given number
+// of released holds along with the given error.
+type stubHoldExpiryAccountService struct {
+	interfaces.AccountService
+
+	released int
+	err      error
+	calls    atomic.Int64
+}
+
+func (s *stubHoldExpiryAccountService) ReleaseExpiredHolds(context.Context) (int, error) {
+	s.calls.Add(1)
+	return s.released, s.err
+}
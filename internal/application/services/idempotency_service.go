@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/application/interfaces"
+	"github.com/KretovDmitry/gophermart/internal/config"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/domain/repositories"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+)
+
+type IdempotencyService struct {
+	repo   repositories.IdempotencyRepository
+	config *config.Config
+	logger logger.Logger
+}
+
+// NewIdempotencyService creates the service remembering
+// responses to the requests with the idempotency key.
+func NewIdempotencyService(
+	repo repositories.IdempotencyRepository,
+	config *config.Config,
+	logger logger.Logger,
+) (*IdempotencyService, error) {
+	if repo == nil {
+		return nil, errors.New("nil dependency: idempotency repository")
+	}
+	if config == nil {
+		return nil, errors.New("nil dependency: config")
+	}
+	if config.Idempotency.TTL <= 0 {
+		return nil, errors.New("idempotency key ttl must be positive")
+	}
+	if config.Idempotency.InProgressTimeout <= 0 {
+		return nil, errors.New("idempotency key in-progress timeout must be positive")
+	}
+
+	return &IdempotencyService{repo: repo, config: config, logger: logger}, nil
+}
+
+var _ interfaces.IdempotencyService = (*IdempotencyService)(nil)
+
+// Begin reserves the key for the request. It returns the response to replay
+// if the request is already handled, nil if it is to be handled now.
+// The key of the request lost without the response is reserved again
+// once the in-progress timeout passes.
+func (s *IdempotencyService) Begin(
+	ctx context.Context, key *entities.IdempotencyKey,
+) (*entities.IdempotentResponse, error) {
+	err := s.repo.CreateIdempotencyKey(ctx, key,
+		s.config.Idempotency.TTL, s.config.Idempotency.InProgressTimeout)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, errs.ErrAlreadyExists) {
+		return nil, fmt.Errorf("create idempotency key: %w", err)
+	}
+
+	saved, err := s.repo.GetIdempotencyKey(ctx, key.UserID, key.Key)
+	if err != nil {
+		// The first request has just failed and released the key.
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, fmt.Errorf("%w: request with idempotency key %q is in progress",
+				errs.ErrDataConflict, key.Key)
+		}
+		return nil, fmt.Errorf("get idempotency key: %w", err)
+	}
+
+	if saved.RequestHash != key.RequestHash {
+		return nil, fmt.Errorf("%w: %q", errs.ErrIdempotencyKeyUsed, key.Key)
+	}
+
+	if saved.Response == nil {
+		return nil, fmt.Errorf("%w: request with idempotency key %q is in progress",
+			errs.ErrDataConflict, key.Key)
+	}
+
+	return saved.Response, nil
+}
+
+// Finish saves the response to replay it on retries. Server errors
+// are not saved: the key is released for the retry to be handled again.
+func (s *IdempotencyService) Finish(
+	ctx context.Context, key *entities.IdempotencyKey, res *entities.IdempotentResponse,
+) error {
+	if res.StatusCode >= http.StatusInternalServerError {
+		if err := s.repo.DeleteIdempotencyKey(ctx, key.UserID, key.Key); err != nil {
+			return fmt.Errorf("delete idempotency key: %w", err)
+		}
+		return nil
+	}
+
+	if err := s.repo.SaveIdempotentResponse(ctx, key.UserID, key.Key, res); err != nil {
+		return fmt.Errorf("save idempotent response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/config"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
+	"github.com/KretovDmitry/gophermart/internal/domain/repositories"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyService(t *testing.T) {
+	keys := newStubIdempotencyRepository()
+
+	logger, _ := logger.NewForTest()
+
+	service, err := NewIdempotencyService(keys, &config.Config{
+		Idempotency: config.Idempotency{TTL: time.Hour, InProgressTimeout: time.Minute},
+	}, logger)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	key := &entities.IdempotencyKey{UserID: testUserID, Key: "key", RequestHash: "hash"}
+	res := &entities.IdempotentResponse{StatusCode: http.StatusOK, ContentType: "application/json", Body: []byte("{}")}
+
+	saved, err := service.Begin(ctx, key)
+	require.NoError(t, err)
+	assert.Nil(t, saved, "first request is handled")
+
+	_, err = service.Begin(ctx, key)
+	assert.ErrorIs(t, err, errs.ErrDataConflict, "retry while the first request is in progress")
+
+	require.NoError(t, service.Finish(ctx, key, res))
+
+	saved, err = service.Begin(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, res, saved, "retry gets the first response")
+
+	other := &entities.IdempotencyKey{UserID: testUserID, Key: "key", RequestHash: "other"}
+	_, err = service.Begin(ctx, other)
+	assert.ErrorIs(t, err, errs.ErrIdempotencyKeyUsed)
+
+	// Same key of another user is independent.
+	saved, err = service.Begin(ctx, &entities.IdempotencyKey{UserID: testUserID + 1, Key: "key", RequestHash: "other"})
+	require.NoError(t, err)
+	assert.Nil(t, saved)
+
+	// Server error releases the key.
+	failed := &entities.IdempotencyKey{UserID: testUserID, Key: "failed", RequestHash: "hash"}
+	_, err = service.Begin(ctx, failed)
+	require.NoError(t, err)
+	require.NoError(t, service.Finish(ctx, failed, &entities.IdempotentResponse{StatusCode: http.StatusInternalServerError}))
+
+	saved, err = service.Begin(ctx, failed)
+	require.NoError(t, err)
+	assert.Nil(t, saved, "failed request is handled again")
+
+	// Key of the request lost without the response is taken over after the timeout.
+	lost := &entities.IdempotencyKey{UserID: testUserID, Key: "lost", RequestHash: "hash"}
+	_, err = service.Begin(ctx, lost)
+	require.NoError(t, err)
+
+	keys.mu.Lock()
+	keys.keys[testUserID]["lost"].CreatedAt = time.Now().Add(-2 * time.Minute)
+	keys.mu.Unlock()
+
+	saved, err = service.Begin(ctx, lost)
+	require.NoError(t, err)
+	assert.Nil(t, saved, "lost request is handled again")
+}
+
+type stubIdempotencyRepository struct {
+	mu   sync.Mutex
+	keys map[user.ID]map[string]*entities.IdempotencyKey
+}
+
+var _ repositories.IdempotencyRepository = (*stubIdempotencyRepository)(nil)
+
+func newStubIdempotencyRepository() *stubIdempotencyRepository {
+	return &stubIdempotencyRepository{keys: make(map[user.ID]map[string]*entities.IdempotencyKey)}
+}
+
+func (r *stubIdempotencyRepository) CreateIdempotencyKey(
+	_ context.Context, key *entities.IdempotencyKey, ttl, inProgressTimeout time.Duration,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if saved, ok := r.keys[key.UserID][key.Key]; ok && time.Since(saved.CreatedAt) < ttl &&
+		(saved.Response != nil || time.Since(saved.CreatedAt) < inProgressTimeout) {
+		return errs.ErrAlreadyExists
+	}
+	if r.keys[key.UserID] == nil {
+		r.keys[key.UserID] = make(map[string]*entities.IdempotencyKey)
+	}
+	r.keys[key.UserID][key.Key] = &entities.IdempotencyKey{
+		UserID:      key.UserID,
+		Key:         key.Key,
+		RequestHash: key.RequestHash,
+		CreatedAt:   time.Now(),
+	}
+
+	return nil
+}
+
+func (r *stubIdempotencyRepository) GetIdempotencyKey(
+	_ context.Context, id user.ID, key string,
+) (*entities.IdempotencyKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	saved, ok := r.keys[id][key]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+	k := *saved
+
+	return &k, nil
+}
+
+func (r *stubIdempotencyRepository) SaveIdempotentResponse(
+	_ context.Context, id user.ID, key string, res *entities.IdempotentResponse,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if saved, ok := r.keys[id][key]; ok && saved.Response == nil {
+		saved.Response = res
+	}
+
+	return nil
+}
+
+func (r *stubIdempotencyRepository) DeleteIdempotencyKey(_ context.Context, id user.ID, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.keys[id], key)
+
+	return nil
+}
+
+func (r *stubIdempotencyRepository) DeleteExpiredIdempotencyKeys(_ context.Context, ttl time.Duration) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, keys := range r.keys {
+		for key, saved := range keys {
+			if time.Since(saved.CreatedAt) >= ttl {
+				delete(keys, key)
+				n++
+			}
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// job runs the task on each tick until it is stopped.
+type job struct {
+	every    time.Duration
+	task     func(ctx context.Context)
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func newJob(every time.Duration, task func(ctx context.Context)) *job {
+	return &job{every: every, task: task, done: make(chan struct{})}
+}
+
+// Run starts running the task on each tick.
+func (j *job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.every)
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-j.done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				j.task(ctx)
+			}
+		}
+	}()
+}
+
+// Stop stops the job and waits for the current run to finish.
+func (j *job) Stop() {
+	j.stopOnce.Do(func() {
+		close(j.done)
+	})
+
+	j.wg.Wait()
+}
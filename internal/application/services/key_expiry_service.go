@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"expvar"
+
+	"github.com/KretovDmitry/gophermart/internal/config"
+	"github.com/KretovDmitry/gophermart/internal/domain/repositories"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+)
+
+// Metrics of the idempotency key expiry job published by expvar:
+// runs, errors and the number of deleted keys.
+var keyExpiryMetrics = expvar.NewMap("idempotency_key_expiry")
+
+type KeyExpiryService struct {
+	*job
+	repo   repositories.IdempotencyRepository
+	logger logger.Logger
+	config *config.Config
+}
+
+// NewKeyExpiryService creates the service deleting
+// idempotency keys older than their ttl.
+func NewKeyExpiryService(
+	repo repositories.IdempotencyRepository,
+	config *config.Config,
+	logger logger.Logger,
+) (*KeyExpiryService, error) {
+	if repo == nil {
+		return nil, errors.New("nil dependency: idempotency repository")
+	}
+	if config == nil {
+		return nil, errors.New("nil dependency: config")
+	}
+	if config.Idempotency.PurgeEvery <= 0 {
+		return nil, errors.New("idempotency key purge interval must be positive")
+	}
+	if config.Idempotency.TTL <= 0 {
+		return nil, errors.New("idempotency key ttl must be positive")
+	}
+
+	s := &KeyExpiryService{repo: repo, logger: logger, config: config}
+	s.job = newJob(config.Idempotency.PurgeEvery, s.expire)
+
+	return s, nil
+}
+
+func (s *KeyExpiryService) expire(ctx context.Context) {
+	keyExpiryMetrics.Add("runs", 1)
+
+	n, err := s.repo.DeleteExpiredIdempotencyKeys(ctx, s.config.Idempotency.TTL)
+	if err != nil {
+		keyExpiryMetrics.Add("errors", 1)
+		s.logger.Errorf("delete expired idempotency keys: %v", err)
+		return
+	}
+
+	if n > 0 {
+		keyExpiryMetrics.Add("deleted", int64(n))
+		s.logger.Infof("deleted %d expired idempotency keys", n)
+	}
+}
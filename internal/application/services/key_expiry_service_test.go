@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/config"
+	"github.com/KretovDmitry/gophermart/internal/domain/repositories"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyExpiryService(t *testing.T) {
+	keys := &stubKeyExpiryRepository{deleted: 3}
+
+	cfg := &config.Config{
+		Idempotency: config.Idempotency{TTL: time.Hour, PurgeEvery: time.Millisecond},
+	}
+
+	logger, _ := logger.NewForTest()
+
+	deleted := func() int64 {
+		if v, ok := keyExpiryMetrics.Get("deleted").(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	before := deleted()
+
+	service, err := NewKeyExpiryService(keys, cfg, logger)
+	require.NoError(t, err)
+
+	service.Run(context.Background())
+
+	require.Eventually(t, func() bool {
+		return len(keys.getTTLs()) > 0
+	}, time.Second, time.Millisecond)
+
+	service.Stop()
+
+	ttls := keys.getTTLs()
+	assert.Equal(t, time.Hour, ttls[0], "keys older than ttl are deleted")
+	assert.Equal(t, before+3*int64(len(ttls)), deleted())
+}
+
+// stubKeyExpiryRepository records the ttl of each purge
+// and reports the given number of keys deleted by it.
+type stubKeyExpiryRepository struct {
+	repositories.IdempotencyRepository
+
+	mu      sync.Mutex
+	deleted int
+	ttls    []time.Duration
+}
+
+func (r *stubKeyExpiryRepository) DeleteExpiredIdempotencyKeys(_ context.Context, ttl time.Duration) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ttls = append(r.ttls, ttl)
+
+	return r.deleted, nil
+}
+
+func (r *stubKeyExpiryRepository) getTTLs() []time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]time.Duration(nil), r.ttls...)
+}
@@ -2,21 +2,29 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
 	"github.com/KretovDmitry/gophermart/internal/application/interfaces"
+	"github.com/KretovDmitry/gophermart/internal/application/params"
 	"github.com/KretovDmitry/gophermart/internal/domain/entities"
 	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
 	"github.com/KretovDmitry/gophermart/internal/domain/repositories"
 	"github.com/KretovDmitry/gophermart/pkg/logger"
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
 )
 
 type OrderService struct {
 	repo   repositories.OrderRepository
+	trm    *manager.Manager
 	logger logger.Logger
 }
 
-func NewOrderService(repo repositories.OrderRepository, logger logger.Logger) (*OrderService, error) {
-	return &OrderService{repo: repo, logger: logger}, nil
+func NewOrderService(
+	repo repositories.OrderRepository, trm *manager.Manager, logger logger.Logger,
+) (*OrderService, error) {
+	return &OrderService{repo: repo, trm: trm, logger: logger}, nil
 }
 
 var _ interfaces.OrderService = (*OrderService)(nil)
@@ -26,7 +34,130 @@ func (s *OrderService) CreateOrder(ctx context.Context, id user.ID, num entities
 	return s.repo.CreateOrder(ctx, id, num)
 }
 
-// Get user's orders.
-func (s *OrderService) GetOrders(ctx context.Context, id user.ID) ([]*entities.Order, error) {
-	return s.repo.GetOrdersByUserID(ctx, id)
+// Create user's orders from the batch of numbers
+// returning results in the same order.
+func (s *OrderService) CreateOrders(
+	ctx context.Context, id user.ID, nums []string,
+) ([]*entities.OrderUpload, error) {
+	uploads := make([]*entities.OrderUpload, len(nums))
+	valid := make([]entities.OrderNumber, 0, len(nums))
+
+	for i, num := range nums {
+		uploads[i] = &entities.OrderUpload{Number: num}
+
+		orderNumber, err := entities.NewOrderNumber(num)
+		if err != nil {
+			uploads[i].Err = err
+			continue
+		}
+
+		valid = append(valid, orderNumber)
+	}
+
+	if len(valid) == 0 {
+		return uploads, nil
+	}
+
+	results, err := s.repo.CreateOrders(ctx, id, valid)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, upload := range uploads {
+		if upload.Err == nil {
+			upload.Err = results[entities.OrderNumber(upload.Number)]
+		}
+	}
+
+	return uploads, nil
+}
+
+// Get page of user's orders and the cursor of the next one, nil if it is the last page.
+func (s *OrderService) GetOrders(
+	ctx context.Context, p *params.ListOrders,
+) ([]*entities.Order, *params.Cursor, error) {
+	if p.Limit == 0 {
+		orders, err := s.repo.ListOrders(ctx, p)
+		return orders, nil, err
+	}
+
+	// One more order tells if there is the next page.
+	page := *p
+	page.Limit++
+
+	orders, err := s.repo.ListOrders(ctx, &page)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(orders) <= p.Limit {
+		return orders, nil, nil
+	}
+
+	orders = orders[:p.Limit]
+	last := orders[len(orders)-1]
+
+	return orders, &params.Cursor{At: last.UploadetAt, ID: last.ID}, nil
+}
+
+// Get user's order. Orders of other users are not found either.
+func (s *OrderService) GetOrder(
+	ctx context.Context, id user.ID, num entities.OrderNumber,
+) (*entities.Order, error) {
+	order, err := s.repo.GetOrderByNumber(ctx, num)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %q", errs.ErrOrderNotFound, num)
+		}
+		return nil, err
+	}
+
+	if order.UserID != id {
+		return nil, fmt.Errorf("%w: %q", errs.ErrOrderNotFound, num)
+	}
+
+	return order, nil
+}
+
+// Get timeline of user's order.
+func (s *OrderService) GetOrderHistory(
+	ctx context.Context, id user.ID, num entities.OrderNumber,
+) ([]*entities.OrderStatusChange, error) {
+	history, err := s.repo.GetOrderHistory(ctx, id, num)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %q", errs.ErrOrderNotFound, num)
+		}
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// Cancel user's order the accrual system hasn't started processing yet.
+// Orders of other users are not found either.
+func (s *OrderService) CancelOrder(ctx context.Context, id user.ID, num entities.OrderNumber) error {
+	return s.trm.Do(ctx, func(ctx context.Context) error {
+		order, err := s.repo.LockOrder(ctx, num)
+		if err != nil {
+			if errors.Is(err, errs.ErrNotFound) {
+				return fmt.Errorf("%w: %q", errs.ErrOrderNotFound, num)
+			}
+			return fmt.Errorf("lock order: %w", err)
+		}
+
+		if order.UserID != id {
+			return fmt.Errorf("%w: %q", errs.ErrOrderNotFound, num)
+		}
+
+		if order.Status != entities.NEW {
+			return fmt.Errorf("%w: %s order can't be cancelled", errs.ErrDataConflict, order.Status)
+		}
+
+		if err = s.repo.CancelOrder(ctx, order); err != nil {
+			return fmt.Errorf("cancel order: %w", err)
+		}
+
+		return nil
+	})
 }
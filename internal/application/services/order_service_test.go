@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/application/params"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
+	"github.com/KretovDmitry/gophermart/internal/domain/repositories"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderServiceGetOrdersPages(t *testing.T) {
+	uploadetAt := time.Now()
+
+	listed := []*entities.Order{
+		{ID: 3, UserID: testUserID, Number: "4561261212345467", UploadetAt: uploadetAt},
+		{ID: 2, UserID: testUserID, Number: "12345678903", UploadetAt: uploadetAt},
+		{ID: 1, UserID: testUserID, Number: "79927398713", UploadetAt: uploadetAt},
+	}
+
+	orders := newStubOrderServiceRepository()
+	orders.listed = listed
+
+	logger, _ := logger.NewForTest()
+
+	service, err := NewOrderService(orders, manager.Must(stubTrFactory), logger)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	p := &params.ListOrders{UserID: testUserID, Limit: 2}
+
+	// The repository returns one more order than the page holds.
+	page, next, err := service.GetOrders(ctx, p)
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	assert.Equal(t, []entities.OrderNumber{listed[0].Number, listed[1].Number}, orderNumbers(page))
+	assert.Equal(t, params.Cursor{At: uploadetAt, ID: 2}, *next)
+	assert.Equal(t, 3, orders.requested[0].Limit, "one more order is requested")
+
+	p.After = next
+	orders.listed = listed[2:]
+
+	page, next, err = service.GetOrders(ctx, p)
+	require.NoError(t, err)
+	assert.Nil(t, next)
+	assert.Equal(t, []entities.OrderNumber{listed[2].Number}, orderNumbers(page))
+	assert.Equal(t, p.After, orders.requested[1].After, "the cursor is passed to the repository")
+
+	// All the orders are returned without limit.
+	orders.listed = listed
+
+	page, next, err = service.GetOrders(ctx, &params.ListOrders{UserID: testUserID})
+	require.NoError(t, err)
+	assert.Nil(t, next)
+	assert.Len(t, page, len(listed))
+	assert.Zero(t, orders.requested[2].Limit)
+}
+
+func TestOrderServiceGetOrder(t *testing.T) {
+	const num entities.OrderNumber = "79927398713"
+
+	orders := newStubOrderServiceRepository()
+	orders.add(testUserID, num, entities.NEW)
+
+	logger, _ := logger.NewForTest()
+
+	service, err := NewOrderService(orders, manager.Must(stubTrFactory), logger)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	order, err := service.GetOrder(ctx, testUserID, num)
+	require.NoError(t, err)
+	assert.Equal(t, num, order.Number)
+
+	// Someone else's order is not found the same as unknown one.
+	_, err = service.GetOrder(ctx, testUserID+1, num)
+	assert.ErrorIs(t, err, errs.ErrOrderNotFound)
+
+	_, err = service.GetOrder(ctx, testUserID, "12345678903")
+	assert.ErrorIs(t, err, errs.ErrOrderNotFound)
+}
+
+func TestOrderServiceCreateOrders(t *testing.T) {
+	orders := newStubOrderServiceRepository()
+	orders.created = map[entities.OrderNumber]error{
+		"4561261212345467": nil,
+		"12345678903":      errs.ErrAlreadyExists,
+		"79927398713":      errs.ErrDataConflict,
+	}
+
+	logger, _ := logger.NewForTest()
+
+	service, err := NewOrderService(orders, manager.Must(stubTrFactory), logger)
+	require.NoError(t, err)
+
+	uploads, err := service.CreateOrders(context.Background(), testUserID, []string{
+		"4561261212345467",
+		"12345678903",
+		"79927398713",
+		"12345",
+		"4561261212345467",
+	})
+	require.NoError(t, err)
+
+	want := []error{nil, errs.ErrAlreadyExists, errs.ErrDataConflict, errs.ErrInvalidOrderNumber, nil}
+	require.Len(t, uploads, len(want))
+	for i, upload := range uploads {
+		assert.ErrorIs(t, upload.Err, want[i], upload.Number)
+		if want[i] == nil {
+			assert.NoError(t, upload.Err, upload.Number)
+		}
+	}
+
+	// Invalid numbers don't reach the repository.
+	assert.Equal(t, []entities.OrderNumber{
+		"4561261212345467", "12345678903", "79927398713", "4561261212345467",
+	}, orders.uploaded)
+}
+
+func TestOrderServiceCancelOrder(t *testing.T) {
+	const (
+		fresh      entities.OrderNumber = "79927398713"
+		processing entities.OrderNumber = "12345678903"
+		foreign    entities.OrderNumber = "4561261212345467"
+	)
+
+	orders := newStubOrderServiceRepository()
+	orders.add(testUserID, fresh, entities.NEW)
+	orders.add(testUserID, processing, entities.PROCESSING)
+	orders.add(testUserID+1, foreign, entities.NEW)
+
+	logger, _ := logger.NewForTest()
+
+	service, err := NewOrderService(orders, manager.Must(stubTrFactory), logger)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	err = service.CancelOrder(ctx, testUserID, processing)
+	assert.ErrorIs(t, err, errs.ErrDataConflict)
+
+	err = service.CancelOrder(ctx, testUserID, foreign)
+	assert.ErrorIs(t, err, errs.ErrOrderNotFound)
+
+	require.NoError(t, service.CancelOrder(ctx, testUserID, fresh))
+	assert.Equal(t, []entities.OrderNumber{fresh}, orders.cancelled)
+
+	err = service.CancelOrder(ctx, testUserID, fresh)
+	assert.ErrorIs(t, err, errs.ErrOrderNotFound)
+}
+
+func orderNumbers(orders []*entities.Order) []entities.OrderNumber {
+	numbers := make([]entities.OrderNumber, len(orders))
+	for i, order := range orders {
+		numbers[i] = order.Number
+	}
+	return numbers
+}
+
+// stubOrderServiceRepository returns the given orders and upload results
+// recording the requests.
+type stubOrderServiceRepository struct {
+	repositories.OrderRepository
+
+	mu        sync.Mutex
+	orders    map[entities.OrderNumber]*entities.Order
+	created   map[entities.OrderNumber]error
+	uploaded  []entities.OrderNumber
+	listed    []*entities.Order
+	requested []params.ListOrders
+	cancelled []entities.OrderNumber
+}
+
+func newStubOrderServiceRepository() *stubOrderServiceRepository {
+	return &stubOrderServiceRepository{orders: make(map[entities.OrderNumber]*entities.Order)}
+}
+
+func (r *stubOrderServiceRepository) add(id user.ID, num entities.OrderNumber, status entities.OrderStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order := entities.NewOrder(id, num)
+	order.ID = len(r.orders) + 1
+	order.Status = status
+	r.orders[num] = order
+}
+
+func (r *stubOrderServiceRepository) CreateOrders(
+	_ context.Context, _ user.ID, nums []entities.OrderNumber,
+) (map[entities.OrderNumber]error, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.uploaded = append(r.uploaded, nums...)
+
+	return r.created, nil
+}
+
+func (r *stubOrderServiceRepository) GetOrderByNumber(
+	_ context.Context, num entities.OrderNumber,
+) (*entities.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, ok := r.orders[num]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+	order := *o
+
+	return &order, nil
+}
+
+func (r *stubOrderServiceRepository) ListOrders(
+	_ context.Context, p *params.ListOrders,
+) ([]*entities.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requested = append(r.requested, *p)
+
+	return slices.Clone(r.listed), nil
+}
+
+func (r *stubOrderServiceRepository) LockOrder(
+	ctx context.Context, num entities.OrderNumber,
+) (*entities.Order, error) {
+	return r.GetOrderByNumber(ctx, num)
+}
+
+func (r *stubOrderServiceRepository) CancelOrder(_ context.Context, order *entities.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.orders, order.Number)
+	r.cancelled = append(r.cancelled, order.Number)
+
+	return nil
+}
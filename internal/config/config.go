@@ -15,10 +15,14 @@ type (
 		// The data source name (DSN) for connecting to the database.
 		DSN string `yaml:"dsn" env:"DATABASE_URI"`
 		// Subconfigs.
-		Accrual    Accrual    `yaml:"accrual"`
-		HTTPServer HTTPServer `yaml:"http_server"`
-		JWT        JWT        `yaml:"jwt"`
-		Logger     Logger     `yaml:"logger"`
+		Accrual     Accrual     `yaml:"accrual"`
+		Admin       Admin       `yaml:"admin"`
+		Expiry      Expiry      `yaml:"expiry"`
+		Holds       Holds       `yaml:"holds"`
+		Idempotency Idempotency `yaml:"idempotency"`
+		HTTPServer  HTTPServer  `yaml:"http_server"`
+		JWT         JWT         `yaml:"jwt"`
+		Logger      Logger      `yaml:"logger"`
 		// Cost to hash the password. Must be grater than 3.
 		PasswordHashCost int `yaml:"password_hash_cost" env-default:"14"`
 		// Allows set env var locally to not run migrations.
@@ -37,7 +41,75 @@ type (
 		// Time interval between burst acrrual API calls.
 		Every time.Duration `yaml:"every" env-default:"10s"`
 		// Number of simultaneous calls to the accrual service.
+		// Sizes both the pool of workers and the burst of the rate limiter.
 		Burst int `yaml:"burst" env-default:"10"`
+		// Unique name of the instance leasing orders. Defaults to host:pid.
+		InstanceID string `yaml:"instance_id" env:"INSTANCE_ID"`
+		// How long claimed orders are leased to the instance.
+		Lease time.Duration `yaml:"lease" env-default:"1m"`
+		// Delays between checks of the same order.
+		Backoff Backoff `yaml:"backoff"`
+		// Secret to verify signatures of the updates pushed by the accrual
+		// service. Empty secret disables the webhook.
+		WebhookSecret string `yaml:"webhook_secret" env:"ACCRUAL_WEBHOOK_SECRET"`
+		// Time interval between polls reconciling orders if updates are pushed.
+		ReconcileEvery time.Duration `yaml:"reconcile_every" env-default:"5m"`
+		// Circuit breaker stopping calls while the accrual service fails.
+		Breaker Breaker `yaml:"breaker"`
+	}
+	// Config for exponential backoff.
+	Backoff struct {
+		// Delay after the first attempt.
+		Base time.Duration `yaml:"base" env-default:"10s"`
+		// Multiplier applied to the delay after each attempt.
+		Factor float64 `yaml:"factor" env-default:"2"`
+		// Maximum delay.
+		Cap time.Duration `yaml:"cap" env-default:"1h"`
+		// Random fraction of the delay to spread checks in time.
+		Jitter float64 `yaml:"jitter" env-default:"0.2"`
+	}
+	// Config for circuit breaker.
+	Breaker struct {
+		// Number of consecutive failures opening the circuit.
+		FailureThreshold int `yaml:"failure_threshold" env-default:"5"`
+		// Time the circuit stays open before trial calls.
+		OpenTimeout time.Duration `yaml:"open_timeout" env-default:"30s"`
+		// Number of successful trial calls closing the circuit.
+		SuccessThreshold int `yaml:"success_threshold" env-default:"1"`
+	}
+	// Config for expiry of orders never resolved by the accrual service.
+	Expiry struct {
+		// Time interval between runs of the expiry job.
+		Every time.Duration `yaml:"every" env-default:"10m"`
+		// Max age of NEW orders. Zero disables expiry of them.
+		NewMaxAge time.Duration `yaml:"new_max_age" env-default:"72h"`
+		// Max age of PROCESSING orders. Zero disables expiry of them.
+		ProcessingMaxAge time.Duration `yaml:"processing_max_age" env-default:"720h"`
+	}
+	// Config for holds of funds.
+	Holds struct {
+		// How long funds are held if the request doesn't set it.
+		DefaultTTL time.Duration `yaml:"default_ttl" env-default:"15m"`
+		// Maximum time funds are held.
+		MaxTTL time.Duration `yaml:"max_ttl" env-default:"24h"`
+		// Time interval between runs of the job releasing expired holds.
+		ReleaseEvery time.Duration `yaml:"release_every" env-default:"1m"`
+	}
+	// Config for idempotency keys of retried requests.
+	Idempotency struct {
+		// How long the response is replayed to the retries with the same key.
+		TTL time.Duration `yaml:"ttl" env-default:"24h"`
+		// How long the key of the request without the response is kept from
+		// retries. After it the request is considered lost and the key is
+		// taken over by the retry.
+		InProgressTimeout time.Duration `yaml:"in_progress_timeout" env-default:"1m"`
+		// Time interval between runs of the job deleting expired keys.
+		PurgeEvery time.Duration `yaml:"purge_every" env-default:"1h"`
+	}
+	// Config for admin endpoints.
+	Admin struct {
+		// Bearer token of the admin. Empty token disables admin endpoints.
+		Token string `yaml:"token" env:"ADMIN_TOKEN"`
 	}
 	// Config for HTTP server.
 	HTTPServer struct {
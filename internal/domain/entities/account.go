@@ -9,4 +9,6 @@ type Account struct {
 	UserID    int
 	Balance   decimal.Decimal
 	Withdrawn decimal.Decimal
+	// Funds reserved by active holds, not included in the balance.
+	Held decimal.Decimal
 }
@@ -10,6 +10,14 @@ type OperationType string
 const (
 	ACCRUAL    OperationType = "ACCRUAL"
 	WITHDRAWAL OperationType = "WITHDRAWAL"
+	// Balance is corrected outside of any order.
+	ADJUSTMENT OperationType = "ADJUSTMENT"
+	// Funds are reserved.
+	HOLD OperationType = "HOLD"
+	// Reserved funds are withdrawn.
+	CAPTURE OperationType = "CAPTURE"
+	// Reserved funds are returned to the balance.
+	RELEASE OperationType = "RELEASE"
 )
 
 type Operation struct {
@@ -29,3 +37,47 @@ func NewWithdrawOperation(
 		Sum:    sum,
 	}
 }
+
+func NewAccrualOperation(
+	id user.ID, order OrderNumber, sum decimal.Decimal,
+) *Operation {
+	return &Operation{
+		UserID: id,
+		Type:   ACCRUAL,
+		Order:  order,
+		Sum:    sum,
+	}
+}
+
+func NewHoldOperation(
+	id user.ID, order OrderNumber, sum decimal.Decimal,
+) *Operation {
+	return &Operation{
+		UserID: id,
+		Type:   HOLD,
+		Order:  order,
+		Sum:    sum,
+	}
+}
+
+func NewCaptureOperation(
+	id user.ID, order OrderNumber, sum decimal.Decimal,
+) *Operation {
+	return &Operation{
+		UserID: id,
+		Type:   CAPTURE,
+		Order:  order,
+		Sum:    sum,
+	}
+}
+
+func NewReleaseOperation(
+	id user.ID, order OrderNumber, sum decimal.Decimal,
+) *Operation {
+	return &Operation{
+		UserID: id,
+		Type:   RELEASE,
+		Order:  order,
+		Sum:    sum,
+	}
+}
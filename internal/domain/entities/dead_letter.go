@@ -0,0 +1,14 @@
+package entities
+
+import "time"
+
+// DeadLetter keeps the accrual system response
+// that can't be understood for investigation.
+type DeadLetter struct {
+	ID         int
+	Order      OrderNumber
+	StatusCode int
+	Body       string
+	Error      string
+	CreatedAt  time.Time
+}
@@ -0,0 +1,32 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
+	"github.com/shopspring/decimal"
+)
+
+type HoldStatus string
+
+const (
+	// Funds are reserved until the hold is captured, released or expires.
+	HoldActive HoldStatus = "ACTIVE"
+	// Reserved funds are withdrawn.
+	HoldCaptured HoldStatus = "CAPTURED"
+	// Reserved funds are returned to the balance.
+	HoldReleased HoldStatus = "RELEASED"
+)
+
+// Hold reserves funds of the user's account for the order.
+type Hold struct {
+	ID        int
+	UserID    user.ID
+	Order     OrderNumber
+	Sum       decimal.Decimal
+	Status    HoldStatus
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	// Whether the hold is past its expiry time when it is read.
+	Expired bool
+}
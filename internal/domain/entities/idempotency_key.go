@@ -0,0 +1,25 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
+)
+
+// IdempotencyKey identifies user's request sent again on retries.
+type IdempotencyKey struct {
+	UserID user.ID
+	Key    string
+	// Hash of the request the key is first used with.
+	RequestHash string
+	// Response to the first request, nil while it is handled.
+	Response  *IdempotentResponse
+	CreatedAt time.Time
+}
+
+// IdempotentResponse is replayed on retries of the request.
+type IdempotentResponse struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
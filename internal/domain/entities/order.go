@@ -1,6 +1,8 @@
 package entities
 
 import (
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/KretovDmitry/gophermart/internal/application/errs"
@@ -19,6 +21,24 @@ const (
 	PROCESSING OrderStatus = "PROCESSING"
 )
 
+// Allowed transitions of the order status. The accrual system may skip
+// PROCESSING, so NEW goes straight to the final status too.
+// INVALID and PROCESSED are final and have no transitions.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	NEW:        {PROCESSING, PROCESSED, INVALID},
+	PROCESSING: {PROCESSED, INVALID},
+}
+
+// IsFinal reports whether the order status never changes.
+func (s OrderStatus) IsFinal() bool {
+	return s == PROCESSED || s == INVALID
+}
+
+// CanTransitionTo reports whether the order in the status may move to another one.
+func (s OrderStatus) CanTransitionTo(to OrderStatus) bool {
+	return slices.Contains(orderTransitions[s], to)
+}
+
 type Order struct {
 	ID         int
 	UserID     user.ID
@@ -26,6 +46,8 @@ type Order struct {
 	Status     OrderStatus
 	Accrual    decimal.Decimal
 	UploadetAt time.Time
+	// Number of checks in the accrual service.
+	Attempts int
 }
 
 func NewOrder(id user.ID, order OrderNumber) *Order {
@@ -36,6 +58,17 @@ func NewOrder(id user.ID, order OrderNumber) *Order {
 	}
 }
 
+// Transition moves the order to the status if the transition is allowed.
+func (o *Order) Transition(to OrderStatus) error {
+	if !o.Status.CanTransitionTo(to) {
+		return fmt.Errorf("%w: order %q %s -> %s", errs.ErrInvalidTransition, o.Number, o.Status, to)
+	}
+
+	o.Status = to
+
+	return nil
+}
+
 type OrderNumber string
 
 func NewOrderNumber(num string) (OrderNumber, error) {
@@ -52,7 +85,9 @@ type UpdateOrderInfo struct {
 	Accrual decimal.Decimal
 }
 
-func NewUpdateInfoFromResponse(r *accrual.UpdateOrderInfo) *UpdateOrderInfo {
+// NewUpdateInfoFromResponse maps the order status of the accrual
+// system to ours. Unknown statuses are rejected.
+func NewUpdateInfoFromResponse(r *accrual.UpdateOrderInfo) (*UpdateOrderInfo, error) {
 	var newStatus OrderStatus
 	switch r.Status {
 	case accrual.PROCESSED:
@@ -61,11 +96,13 @@ func NewUpdateInfoFromResponse(r *accrual.UpdateOrderInfo) *UpdateOrderInfo {
 		newStatus = INVALID
 	case accrual.PROCESSING, accrual.REGISTERED:
 		newStatus = PROCESSING
+	default:
+		return nil, fmt.Errorf("%w: unknown order status %q", errs.ErrInvalidRequest, r.Status)
 	}
 
 	return &UpdateOrderInfo{
 		Number:  OrderNumber(r.Order),
 		Status:  newStatus,
 		Accrual: r.Accrual,
-	}
+	}, nil
 }
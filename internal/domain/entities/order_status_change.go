@@ -0,0 +1,16 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// OrderStatusChange is an entry of the order timeline.
+type OrderStatusChange struct {
+	Status  OrderStatus
+	Accrual decimal.Decimal
+	// Why the status was changed by the system itself, if so.
+	Reason    string
+	ChangedAt time.Time
+}
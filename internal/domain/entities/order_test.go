@@ -0,0 +1,43 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderTransition(t *testing.T) {
+	tests := []struct {
+		from, to OrderStatus
+		want     bool
+	}{
+		{NEW, PROCESSING, true},
+		{NEW, PROCESSED, true},
+		{NEW, INVALID, true},
+		{PROCESSING, PROCESSED, true},
+		{PROCESSING, INVALID, true},
+		{PROCESSING, NEW, false},
+		{PROCESSING, PROCESSING, false},
+		{PROCESSED, PROCESSING, false},
+		{PROCESSED, INVALID, false},
+		{INVALID, PROCESSED, false},
+		{NEW, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			order := &Order{Number: "79927398713", Status: tt.from}
+
+			err := order.Transition(tt.to)
+
+			if tt.want {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.to, order.Status)
+			} else {
+				assert.ErrorIs(t, err, errs.ErrInvalidTransition)
+				assert.Equal(t, tt.from, order.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package entities
+
+// OrderUpload is the result of uploading the order number in the batch.
+type OrderUpload struct {
+	Number string
+	// Nil if the order is accepted.
+	Err error
+}
@@ -0,0 +1,19 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// StatementEntry is the account operation with the balance after it.
+type StatementEntry struct {
+	ID          int
+	Type        OperationType
+	Order       OrderNumber
+	Sum         decimal.Decimal
+	Balance     decimal.Decimal
+	ProcessedAt time.Time
+	// Restored from the order, ProcessedAt is the upload time then.
+	Backfilled bool
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 
+	"github.com/KretovDmitry/gophermart/internal/application/params"
 	"github.com/KretovDmitry/gophermart/internal/domain/entities"
 	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
 	"github.com/shopspring/decimal"
@@ -13,6 +14,10 @@ type AccountRepository interface {
 	GetAccountByUserID(context.Context, user.ID) (*entities.Account, error)
 	Withdraw(context.Context, user.ID, decimal.Decimal) error
 	GetWithdrawalsByUserID(context.Context, user.ID) ([]*entities.Withdrawal, error)
+	GetStatement(context.Context, *params.Statement) ([]*entities.StatementEntry, error)
 	SaveAccountOperation(context.Context, *entities.Operation) error
 	AddToAccount(context.Context, user.ID, decimal.Decimal) error
+	HoldFunds(context.Context, user.ID, decimal.Decimal) error
+	CaptureHeldFunds(context.Context, user.ID, decimal.Decimal) error
+	ReleaseHeldFunds(context.Context, user.ID, decimal.Decimal) error
 }
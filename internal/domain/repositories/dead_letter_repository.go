@@ -0,0 +1,14 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+)
+
+type DeadLetterRepository interface {
+	SaveDeadLetter(context.Context, *entities.DeadLetter) error
+	GetDeadLetters(context.Context) ([]*entities.DeadLetter, error)
+	GetDeadLetter(ctx context.Context, id int) (*entities.DeadLetter, error)
+	DeleteDeadLetter(ctx context.Context, id int) error
+}
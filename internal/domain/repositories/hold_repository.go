@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
+)
+
+type HoldRepository interface {
+	CreateHold(ctx context.Context, hold *entities.Hold, ttl time.Duration) error
+	LockReleasedOrder(ctx context.Context, id user.ID, num entities.OrderNumber) error
+	LockHold(ctx context.Context, id int) (*entities.Hold, error)
+	ResolveHold(ctx context.Context, id int, status entities.HoldStatus) error
+	GetExpiredHolds(ctx context.Context, limit int) ([]int, error)
+}
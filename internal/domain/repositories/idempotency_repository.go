@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
+)
+
+type IdempotencyRepository interface {
+	CreateIdempotencyKey(ctx context.Context, key *entities.IdempotencyKey, ttl, inProgressTimeout time.Duration) error
+	GetIdempotencyKey(ctx context.Context, id user.ID, key string) (*entities.IdempotencyKey, error)
+	SaveIdempotentResponse(ctx context.Context, id user.ID, key string, res *entities.IdempotentResponse) error
+	DeleteIdempotencyKey(ctx context.Context, id user.ID, key string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context, ttl time.Duration) (int, error)
+}
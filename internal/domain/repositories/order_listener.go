@@ -0,0 +1,11 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+)
+
+type OrderListener interface {
+	ListenCreatedOrders(context.Context) <-chan entities.OrderNumber
+}
@@ -2,14 +2,31 @@ package repositories
 
 import (
 	"context"
+	"time"
 
+	"github.com/KretovDmitry/gophermart/internal/application/params"
 	"github.com/KretovDmitry/gophermart/internal/domain/entities"
 	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
+	"github.com/shopspring/decimal"
 )
 
 type OrderRepository interface {
 	CreateOrder(context.Context, user.ID, entities.OrderNumber) error
-	GetOrdersByUserID(context.Context, user.ID) ([]*entities.Order, error)
-	GetUnprocessedOrders(ctx context.Context, limit, offset int) ([]*entities.Order, error)
+	CreateOrders(context.Context, user.ID, []entities.OrderNumber) (map[entities.OrderNumber]error, error)
+	CreateWithdrawalOrder(context.Context, user.ID, entities.OrderNumber) error
+	GetOrderByNumber(context.Context, entities.OrderNumber) (*entities.Order, error)
+	ListOrders(context.Context, *params.ListOrders) ([]*entities.Order, error)
+	ClaimUnprocessedOrders(ctx context.Context, owner string, lease time.Duration, after, limit int) ([]*entities.Order, int, error)
+	ClaimOrder(ctx context.Context, owner string, lease time.Duration, num entities.OrderNumber) (*entities.Order, error)
+	RescheduleOrder(ctx context.Context, num entities.OrderNumber, owner string, delay time.Duration, lastErr string) error
+	ReleaseOrder(ctx context.Context, num entities.OrderNumber, owner string) error
+	DeferOrder(ctx context.Context, num entities.OrderNumber, owner string, delay time.Duration) error
+	ParkOrder(ctx context.Context, num entities.OrderNumber, owner string, lastErr string) error
+	UnparkOrder(context.Context, entities.OrderNumber) error
+	LockOrder(context.Context, entities.OrderNumber) (*entities.Order, error)
 	UpdateOrder(context.Context, *entities.UpdateOrderInfo) (user.ID, error)
+	CancelOrder(context.Context, *entities.Order) error
+	SaveStatusChange(ctx context.Context, orderID int, status entities.OrderStatus, accrual decimal.Decimal) error
+	GetOrderHistory(context.Context, user.ID, entities.OrderNumber) ([]*entities.OrderStatusChange, error)
+	ExpireOrders(ctx context.Context, status entities.OrderStatus, maxAge time.Duration, reason string) (int, error)
 }
@@ -0,0 +1,147 @@
+package accrual
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/application/interfaces"
+	"github.com/KretovDmitry/gophermart/internal/config"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/interface/api/rest/response/accrual"
+)
+
+// Client calls the accrual system over HTTP.
+type Client struct {
+	client  *http.Client
+	address string
+}
+
+func NewClient(config *config.Config) (*Client, error) {
+	if config == nil {
+		return nil, errors.New("nil dependency: config")
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("create new cookie jar: %w", err)
+	}
+
+	client := &http.Client{
+		Jar:     jar,
+		Timeout: config.Accrual.Timeout,
+	}
+
+	return &Client{client: client, address: config.Accrual.Address}, nil
+}
+
+var _ interfaces.AccrualClient = (*Client)(nil)
+
+// GetOrderInfo (GET /api/orders/{number} HTTP/1.1).
+func (c *Client) GetOrderInfo(
+	ctx context.Context, num entities.OrderNumber,
+) (*entities.UpdateOrderInfo, error) {
+	url := fmt.Sprintf("%s/api/orders/%s", c.address, num)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusTooManyRequests:
+		return nil, newRateLimitError(res)
+	case res.StatusCode == http.StatusNoContent:
+		return nil, errs.ErrNotFound
+	// The accrual system is failing, try again later.
+	case res.StatusCode >= http.StatusInternalServerError:
+		return nil, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	// Anything else the accrual system is not expected to answer.
+	malformed := func(err error) error {
+		return &errs.MalformedResponseError{
+			StatusCode: res.StatusCode,
+			Body:       strings.ToValidUTF8(string(body), "\uFFFD"),
+			Err:        err,
+		}
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, malformed(fmt.Errorf("unexpected status code: %d", res.StatusCode))
+	}
+
+	payload := new(accrual.UpdateOrderInfo)
+
+	if err = json.Unmarshal(body, payload); err != nil {
+		return nil, malformed(fmt.Errorf("decode response: %w", err))
+	}
+
+	if payload.Order != string(num) {
+		return nil, malformed(fmt.Errorf("response for order %q", payload.Order))
+	}
+
+	info, err := entities.NewUpdateInfoFromResponse(payload)
+	if err != nil {
+		return nil, malformed(err)
+	}
+
+	return info, nil
+}
+
+// Maximum size of the response body kept for investigation.
+const maxBodySize = 64 << 10
+
+// Default pause if the accrual system doesn't specify Retry-After.
+const defaultRetryAfter = time.Minute
+
+// Body of the rate limited response: "No more than N requests per minute allowed".
+var rateLimitBody = regexp.MustCompile(`No more than (\d+) requests per minute`)
+
+// newRateLimitError parses the limit from
+// the Retry-After header and the response body.
+func newRateLimitError(res *http.Response) *errs.RateLimitError {
+	rateLimitErr := &errs.RateLimitError{RetryAfter: defaultRetryAfter}
+
+	// Retry-After is either delay in seconds or HTTP date.
+	if v := res.Header.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+			rateLimitErr.RetryAfter = time.Duration(seconds) * time.Second
+		} else if date, err := http.ParseTime(v); err == nil {
+			rateLimitErr.RetryAfter = max(time.Until(date), 0)
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1024))
+	if err != nil {
+		return rateLimitErr
+	}
+
+	if m := rateLimitBody.FindSubmatch(body); m != nil {
+		if n, err := strconv.Atoi(string(m[1])); err == nil {
+			rateLimitErr.RequestsPerMinute = n
+		}
+	}
+
+	return rateLimitErr
+}
@@ -0,0 +1,168 @@
+// Package memory provides the scriptable in-memory accrual system for tests.
+package memory
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/application/interfaces"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/interface/api/rest/response/accrual"
+	"github.com/shopspring/decimal"
+)
+
+// Response of the accrual system scripted for the order.
+type Response struct {
+	// Order status reported by the accrual system.
+	Status accrual.OrderStatus
+	// Calculated accrual.
+	Accrual decimal.Decimal
+	// Error returned instead of the order info, if not nil.
+	Err error
+	// Delay before the response.
+	Latency time.Duration
+}
+
+// Registered order not yet calculated.
+func Registered() Response {
+	return Response{Status: accrual.REGISTERED}
+}
+
+// Processing order.
+func Processing() Response {
+	return Response{Status: accrual.PROCESSING}
+}
+
+// Invalid order not accepted for calculation.
+func Invalid() Response {
+	return Response{Status: accrual.INVALID}
+}
+
+// Processed order with the given accrual.
+func Processed(sum decimal.Decimal) Response {
+	return Response{Status: accrual.PROCESSED, Accrual: sum}
+}
+
+// NotRegistered order (204 No Content).
+func NotRegistered() Response {
+	return Response{Err: errs.ErrNotFound}
+}
+
+// RateLimited response (429 Too Many Requests).
+func RateLimited(retryAfter time.Duration, requestsPerMinute int) Response {
+	return Response{Err: &errs.RateLimitError{
+		RetryAfter:        retryAfter,
+		RequestsPerMinute: requestsPerMinute,
+	}}
+}
+
+// Failed response, e.g. network error.
+func Failed(err error) Response {
+	return Response{Err: err}
+}
+
+// Malformed response the accrual service can't understand.
+func Malformed(statusCode int, body string) Response {
+	return Response{Err: &errs.MalformedResponseError{
+		StatusCode: statusCode,
+		Body:       body,
+		Err:        errors.New("invalid character in response body"),
+	}}
+}
+
+// WithLatency delays the response.
+func (r Response) WithLatency(d time.Duration) Response {
+	r.Latency = d
+	return r
+}
+
+// Client is the in-memory accrual system. Responses scripted for the order
+// are returned one by one with the last one repeated. Orders without script
+// are not registered.
+type Client struct {
+	mu      sync.Mutex
+	scripts map[entities.OrderNumber][]Response
+	calls   map[entities.OrderNumber]int
+}
+
+func NewClient() *Client {
+	return &Client{
+		scripts: make(map[entities.OrderNumber][]Response),
+		calls:   make(map[entities.OrderNumber]int),
+	}
+}
+
+var _ interfaces.AccrualClient = (*Client)(nil)
+
+// Script sets responses for the order.
+func (c *Client) Script(num entities.OrderNumber, responses ...Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.scripts[num] = responses
+}
+
+// Calls returns the number of calls made for the order.
+func (c *Client) Calls(num entities.OrderNumber) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.calls[num]
+}
+
+func (c *Client) GetOrderInfo(
+	ctx context.Context, num entities.OrderNumber,
+) (*entities.UpdateOrderInfo, error) {
+	res := c.next(num)
+
+	if res.Latency > 0 {
+		timer := time.NewTimer(res.Latency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if res.Err != nil {
+		return nil, res.Err
+	}
+
+	info, err := entities.NewUpdateInfoFromResponse(&accrual.UpdateOrderInfo{
+		Order:   string(num),
+		Status:  res.Status,
+		Accrual: res.Accrual,
+	})
+	if err != nil {
+		return nil, &errs.MalformedResponseError{
+			StatusCode: http.StatusOK,
+			Body:       string(res.Status),
+			Err:        err,
+		}
+	}
+
+	return info, nil
+}
+
+func (c *Client) next(num entities.OrderNumber) Response {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls[num]++
+
+	script := c.scripts[num]
+	switch len(script) {
+	case 0:
+		return NotRegistered()
+	case 1:
+		return script[0]
+	default:
+		c.scripts[num] = script[1:]
+		return script[0]
+	}
+}
@@ -4,13 +4,17 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 
 	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/application/params"
 	"github.com/KretovDmitry/gophermart/internal/domain/entities"
 	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
 	"github.com/KretovDmitry/gophermart/internal/domain/repositories"
 	"github.com/KretovDmitry/gophermart/pkg/logger"
 	trmsql "github.com/avito-tech/go-transaction-manager/drivers/sql/v2"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/shopspring/decimal"
 )
 
@@ -40,7 +44,7 @@ func (r *AccountRepository) GetAccountByUserID(
 ) (*entities.Account, error) {
 	const query = `
 		SELECT
-			id, user_id, balance, withdrawn
+			id, user_id, balance, withdrawn, held
 		FROM
 			accounts
 		WHERE
@@ -54,6 +58,7 @@ func (r *AccountRepository) GetAccountByUserID(
 		&account.UserID,
 		&account.Balance,
 		&account.Withdrawn,
+		&account.Held,
 	)
 	if err != nil {
 		return nil, err
@@ -62,6 +67,9 @@ func (r *AccountRepository) GetAccountByUserID(
 	return account, nil
 }
 
+// Withdraw debits the account if the balance covers the sum. The row lock
+// taken by the update makes concurrent withdrawals recheck the balance
+// updated by each other, so the account is never overdrawn.
 func (r *AccountRepository) Withdraw(
 	ctx context.Context, userID user.ID, sum decimal.Decimal,
 ) error {
@@ -73,25 +81,11 @@ func (r *AccountRepository) Withdraw(
 			withdrawn = withdrawn + $1
 		WHERE
 			user_id = $2
-		RETURNING
-			balance;
+		AND
+			balance >= $1
 	`
 
-	var updatedBalance decimal.Decimal
-
-	err := r.getter.
-		DefaultTrOrDB(ctx, r.db).
-		QueryRowContext(ctx, query, sum, userID).
-		Scan(&updatedBalance)
-	if err != nil {
-		return err
-	}
-
-	if updatedBalance.LessThan(decimal.NewFromInt(0)) {
-		return errs.ErrNotEnoughFunds
-	}
-
-	return nil
+	return r.moveFunds(ctx, query, userID, sum)
 }
 
 func (r *AccountRepository) SaveAccountOperation(
@@ -102,15 +96,29 @@ func (r *AccountRepository) SaveAccountOperation(
 			(account_id, operation, order_number, sum)
 		VALUES
 			((SELECT id FROM accounts WHERE user_id = $1), $2, $3, $4)
+		ON CONFLICT (order_number) WHERE operation = 'ACCRUAL'
+			DO NOTHING
 	`
 
-	_, err := r.getter.
+	res, err := r.getter.
 		DefaultTrOrDB(ctx, r.db).
 		ExecContext(ctx, query, op.UserID, op.Type, op.Order, op.Sum)
 	if err != nil {
 		return err
 	}
 
+	// Only one accrual per order is allowed by the unique index,
+	// so nothing inserted means the order has already been credited.
+	// Conflicts on any other operation surface as errors above.
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %s operation for order %q",
+			errs.ErrAlreadyExists, op.Type, op.Order)
+	}
+
 	return nil
 }
 
@@ -123,7 +131,7 @@ func (r *AccountRepository) GetWithdrawalsByUserID(
 		FROM
 			account_operations
 		WHERE
-			operation = 'WITHDRAWAL'
+			operation IN ('WITHDRAWAL', 'CAPTURE')
 		AND
 			account_id = (SELECT id FROM accounts WHERE user_id = $1)
 		ORDER BY processed_at DESC
@@ -168,6 +176,122 @@ func (r *AccountRepository) GetWithdrawalsByUserID(
 	return withdrawals, nil
 }
 
+// GetStatement returns the page of user's account operations matching the filter
+// sorted by processing time, each with the balance after the operation.
+// It returns errs.ErrNotFound if there are none.
+func (r *AccountRepository) GetStatement(
+	ctx context.Context, p *params.Statement,
+) ([]*entities.StatementEntry, error) {
+	// The running balance is calculated over the whole
+	// history before the operations are filtered.
+	const query = `
+		SELECT
+			id,
+			operation,
+			-- Adjustments have no order.
+			COALESCE(order_number, ''),
+			sum,
+			balance,
+			processed_at,
+			backfilled
+		FROM (
+			SELECT
+				id,
+				operation,
+				order_number,
+				sum,
+				SUM(
+					CASE operation
+						WHEN 'ACCRUAL' THEN sum
+						WHEN 'RELEASE' THEN sum
+						WHEN 'ADJUSTMENT' THEN sum
+						-- Captured funds have left the balance on hold.
+						WHEN 'CAPTURE' THEN 0
+						ELSE -sum
+					END
+				) OVER (ORDER BY processed_at, id) AS balance,
+				processed_at,
+				backfilled
+			FROM
+				account_operations
+			WHERE
+				account_id = (SELECT id FROM accounts WHERE user_id = $1)
+		) statement
+		WHERE
+			($2::timestamp IS NULL OR processed_at >= $2)
+		AND
+			($3::timestamp IS NULL OR processed_at < $3)
+		AND
+			%s
+		ORDER BY
+			%s
+		LIMIT
+			$6
+	`
+
+	// Keyset pagination: the page starts right after the cursor.
+	after, order := `($4::timestamp IS NULL OR (processed_at, id) < ($4, $5))`, `processed_at DESC, id DESC`
+	if p.Sort == params.SortAsc {
+		after, order = `($4::timestamp IS NULL OR (processed_at, id) > ($4, $5))`, `processed_at, id`
+	}
+
+	var afterAt sql.NullTime
+	var afterID int
+	if p.After != nil {
+		afterAt = sql.NullTime{Time: p.After.At, Valid: true}
+		afterID = p.After.ID
+	}
+
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(query, after, order),
+		p.UserID,
+		sql.NullTime{Time: p.From, Valid: !p.From.IsZero()},
+		sql.NullTime{Time: p.To, Valid: !p.To.IsZero()},
+		afterAt,
+		afterID,
+		sql.NullInt64{Int64: int64(p.Limit), Valid: p.Limit > 0},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*entities.StatementEntry, 0, p.Limit)
+
+	for rows.Next() {
+		e := new(entities.StatementEntry)
+		err = rows.Scan(
+			&e.ID,
+			&e.Type,
+			&e.Order,
+			&e.Sum,
+			&e.Balance,
+			&e.ProcessedAt,
+			&e.Backfilled,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	defer func() {
+		if err = rows.Close(); err != nil {
+			r.logger.Errorf("close rows: %s", err)
+		}
+	}()
+
+	// Rows.Err will report the last error encountered by Rows.Scan.
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, errs.ErrNotFound
+	}
+
+	return entries, nil
+}
+
 func (r *AccountRepository) CreateAccount(ctx context.Context, id user.ID) error {
 	const query = `
 		INSERT INTO accounts
@@ -178,6 +302,12 @@ func (r *AccountRepository) CreateAccount(ctx context.Context, id user.ID) error
 
 	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, id)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == pgerrcode.UniqueViolation {
+				return fmt.Errorf("%w: account of user %d", errs.ErrAlreadyExists, id)
+			}
+		}
 		return err
 	}
 
@@ -203,3 +333,89 @@ func (r *AccountRepository) AddToAccount(
 
 	return nil
 }
+
+// HoldFunds moves the sum from the balance to the held funds
+// if the balance covers it.
+func (r *AccountRepository) HoldFunds(
+	ctx context.Context, id user.ID, sum decimal.Decimal,
+) error {
+	const query = `
+		UPDATE
+			accounts
+		SET
+			balance = balance - $1,
+			held = held + $1
+		WHERE
+			user_id = $2
+		AND
+			balance >= $1
+	`
+
+	return r.moveFunds(ctx, query, id, sum)
+}
+
+// CaptureHeldFunds withdraws the held sum.
+func (r *AccountRepository) CaptureHeldFunds(
+	ctx context.Context, id user.ID, sum decimal.Decimal,
+) error {
+	const query = `
+		UPDATE
+			accounts
+		SET
+			held = held - $1,
+			withdrawn = withdrawn + $1
+		WHERE
+			user_id = $2
+		AND
+			held >= $1
+	`
+
+	return r.moveFunds(ctx, query, id, sum)
+}
+
+// ReleaseHeldFunds returns the held sum to the balance.
+func (r *AccountRepository) ReleaseHeldFunds(
+	ctx context.Context, id user.ID, sum decimal.Decimal,
+) error {
+	const query = `
+		UPDATE
+			accounts
+		SET
+			balance = balance + $1,
+			held = held - $1
+		WHERE
+			user_id = $2
+		AND
+			held >= $1
+	`
+
+	return r.moveFunds(ctx, query, id, sum)
+}
+
+// moveFunds runs the conditional update of the account. Nothing
+// updated means the account doesn't have the funds to move.
+func (r *AccountRepository) moveFunds(
+	ctx context.Context, query string, id user.ID, sum decimal.Decimal,
+) error {
+	res, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, sum, id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == pgerrcode.CheckViolation {
+				return errs.ErrNotEnoughFunds
+			}
+		}
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return errs.ErrNotEnoughFunds
+	}
+
+	return nil
+}
@@ -0,0 +1,275 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/application/params"
+	"github.com/KretovDmitry/gophermart/internal/config"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
+	"github.com/KretovDmitry/gophermart/internal/infrastructure/db/postgres"
+	"github.com/KretovDmitry/gophermart/migrations"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+	trmsql "github.com/avito-tech/go-transaction-manager/drivers/sql/v2"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The tests run against the database given by TEST_DATABASE_URI.
+// They are skipped without it.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URI")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URI is not set")
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	err = migrations.Up(db, &config.Config{MigrateOnStart: true, Migrations: "."})
+	if !errors.Is(err, migrate.ErrNoChange) {
+		require.NoError(t, err)
+	}
+
+	return db
+}
+
+// testUser creates the user deleted with everything it owns after the test.
+func testUser(t *testing.T, db *sql.DB, prefix string) user.ID {
+	t.Helper()
+
+	var id user.ID
+	err := db.QueryRow(
+		`INSERT INTO users (login, password) VALUES ($1, '') RETURNING id`,
+		fmt.Sprintf("%s-%d", prefix, time.Now().UnixNano()),
+	).Scan(&id)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_, _ = db.Exec(`DELETE FROM holds WHERE account_id IN (SELECT id FROM accounts WHERE user_id = $1)`, id)
+		_, _ = db.Exec(`DELETE FROM account_operations WHERE account_id IN (SELECT id FROM accounts WHERE user_id = $1)`, id)
+		_, _ = db.Exec(`DELETE FROM orders WHERE user_id = $1`, id)
+		_, _ = db.Exec(`DELETE FROM order_cancellations WHERE user_id = $1`, id)
+		_, _ = db.Exec(`DELETE FROM accounts WHERE user_id = $1`, id)
+		_, _ = db.Exec(`DELETE FROM users WHERE id = $1`, id)
+	})
+
+	return id
+}
+
+var testOrderSeq atomic.Int64
+
+// testOrderNumber returns the order number unique across test runs.
+func testOrderNumber() entities.OrderNumber {
+	return entities.OrderNumber(strconv.FormatInt(time.Now().UnixNano(), 10) +
+		strconv.FormatInt(testOrderSeq.Add(1), 10))
+}
+
+func TestAccountRepositoryConcurrentWithdraw(t *testing.T) {
+	const (
+		goroutines = 50
+		balance    = 100
+		sum        = 7
+	)
+
+	db := testDB(t)
+	ctx := context.Background()
+	id := testUser(t, db, "withdraw-stress")
+
+	logger, _ := logger.NewForTest()
+
+	repo, err := postgres.NewAccountRepository(db, trmsql.DefaultCtxGetter, logger)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.CreateAccount(ctx, id))
+	require.ErrorIs(t, repo.CreateAccount(ctx, id), errs.ErrAlreadyExists, "one account per user")
+	require.NoError(t, repo.AddToAccount(ctx, id, decimal.NewFromInt(balance)))
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+
+	start := make(chan struct{})
+
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			err := repo.Withdraw(ctx, id, decimal.NewFromInt(sum))
+			if err != nil {
+				assert.ErrorIs(t, err, errs.ErrNotEnoughFunds)
+				return
+			}
+
+			mu.Lock()
+			succeeded++
+			mu.Unlock()
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	assert.Equal(t, balance/sum, succeeded)
+
+	account, err := repo.GetAccountByUserID(ctx, id)
+	require.NoError(t, err)
+	assert.True(t, account.Balance.Equal(decimal.NewFromInt(balance%sum)), account.Balance)
+	assert.True(t, account.Withdrawn.Equal(decimal.NewFromInt(balance-balance%sum)), account.Withdrawn)
+}
+
+func TestAccountRepositorySaveAccountOperationOnce(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	id := testUser(t, db, "accrual-once")
+	num := testOrderNumber()
+
+	_, err := db.ExecContext(ctx, `INSERT INTO orders (user_id, number) VALUES ($1, $2)`, id, num)
+	require.NoError(t, err)
+
+	logger, _ := logger.NewForTest()
+
+	repo, err := postgres.NewAccountRepository(db, trmsql.DefaultCtxGetter, logger)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.CreateAccount(ctx, id))
+
+	accrual := entities.NewAccrualOperation(id, num, decimal.NewFromInt(500))
+	require.NoError(t, repo.SaveAccountOperation(ctx, accrual))
+
+	// The second accrual is ignored by the unique index.
+	err = repo.SaveAccountOperation(ctx, accrual)
+	require.ErrorIs(t, err, errs.ErrAlreadyExists)
+
+	// Other operations on the order are not limited by it.
+	withdrawal := entities.NewWithdrawOperation(id, num, decimal.NewFromInt(100))
+	require.NoError(t, repo.SaveAccountOperation(ctx, withdrawal))
+
+	var accruals int
+	err = db.QueryRowContext(ctx,
+		`SELECT count(*) FROM account_operations WHERE order_number = $1 AND operation = 'ACCRUAL'`, num,
+	).Scan(&accruals)
+	require.NoError(t, err)
+	assert.Equal(t, 1, accruals)
+}
+
+func TestAccountRepositoryStatementAdjustment(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	id := testUser(t, db, "statement-adjustment")
+	num := testOrderNumber()
+
+	_, err := db.ExecContext(ctx, `INSERT INTO orders (user_id, number) VALUES ($1, $2)`, id, num)
+	require.NoError(t, err)
+
+	logger, _ := logger.NewForTest()
+
+	repo, err := postgres.NewAccountRepository(db, trmsql.DefaultCtxGetter, logger)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.CreateAccount(ctx, id))
+
+	// The overdraft the old code allowed and its write-off.
+	withdrawal := entities.NewWithdrawOperation(id, num, decimal.NewFromInt(300))
+	require.NoError(t, repo.SaveAccountOperation(ctx, withdrawal))
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO account_operations (account_id, operation, sum)
+		SELECT id, 'ADJUSTMENT', 300 FROM accounts WHERE user_id = $1`, id)
+	require.NoError(t, err)
+
+	// Only adjustments go without an order.
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO account_operations (account_id, operation, sum)
+		SELECT id, 'WITHDRAWAL', 1 FROM accounts WHERE user_id = $1`, id)
+	require.Error(t, err)
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO account_operations (account_id, operation, order_number, sum)
+		SELECT id, 'ADJUSTMENT', $2, 1 FROM accounts WHERE user_id = $1`, id, num)
+	require.Error(t, err)
+
+	entries, err := repo.GetStatement(ctx, &params.Statement{UserID: id, Sort: params.SortAsc})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, entities.WITHDRAWAL, entries[0].Type)
+	assert.True(t, entries[0].Balance.Equal(decimal.NewFromInt(-300)), entries[0].Balance)
+
+	assert.Equal(t, entities.ADJUSTMENT, entries[1].Type)
+	assert.Empty(t, entries[1].Order)
+	assert.True(t, entries[1].Balance.IsZero(), "statement ends at the balance: %s", entries[1].Balance)
+}
+
+func TestAccountRepositoryGetStatementPages(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	id := testUser(t, db, "statement-pages")
+
+	logger, _ := logger.NewForTest()
+
+	repo, err := postgres.NewAccountRepository(db, trmsql.DefaultCtxGetter, logger)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.CreateAccount(ctx, id))
+
+	for i := 0; i < 5; i++ {
+		num := testOrderNumber()
+		_, err = db.ExecContext(ctx, `INSERT INTO orders (user_id, number) VALUES ($1, $2)`, id, num)
+		require.NoError(t, err)
+		require.NoError(t, repo.SaveAccountOperation(ctx, entities.NewAccrualOperation(id, num, decimal.NewFromInt(10))))
+	}
+
+	// Processed at the same time, ID breaks ties.
+	_, err = db.ExecContext(ctx, `
+		UPDATE account_operations SET processed_at = date_trunc('second', CURRENT_TIMESTAMP)
+		WHERE account_id = (SELECT id FROM accounts WHERE user_id = $1)`, id)
+	require.NoError(t, err)
+
+	all, err := repo.GetStatement(ctx, &params.Statement{UserID: id, Sort: params.SortAsc})
+	require.NoError(t, err)
+	require.Len(t, all, 5)
+
+	p := &params.Statement{UserID: id, Limit: 2, Sort: params.SortAsc}
+
+	var got []*entities.StatementEntry
+	for {
+		page, err := repo.GetStatement(ctx, p)
+		if errors.Is(err, errs.ErrNotFound) {
+			break
+		}
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(page), p.Limit)
+
+		got = append(got, page...)
+
+		last := page[len(page)-1]
+		p.After = &params.Cursor{At: last.ProcessedAt, ID: last.ID}
+	}
+
+	require.Len(t, got, len(all), "every operation is listed once across pages")
+	for i, e := range got {
+		assert.Equal(t, all[i].ID, e.ID)
+		// The running balance doesn't depend on the page.
+		assert.True(t, decimal.NewFromInt(int64(10*(i+1))).Equal(e.Balance), e.Balance)
+	}
+}
@@ -0,0 +1,182 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/domain/repositories"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+	trmsql "github.com/avito-tech/go-transaction-manager/drivers/sql/v2"
+)
+
+type DeadLetterRepository struct {
+	db     *sql.DB
+	getter *trmsql.CtxGetter
+	logger logger.Logger
+}
+
+func NewDeadLetterRepository(
+	db *sql.DB, getter *trmsql.CtxGetter, logger logger.Logger,
+) (*DeadLetterRepository, error) {
+	if db == nil {
+		return nil, errors.New("nil dependency: database")
+	}
+	if getter == nil {
+		return nil, errors.New("nil dependency: transaction getter")
+	}
+
+	return &DeadLetterRepository{db: db, getter: getter, logger: logger}, nil
+}
+
+var _ repositories.DeadLetterRepository = (*DeadLetterRepository)(nil)
+
+func (r *DeadLetterRepository) SaveDeadLetter(
+	ctx context.Context, letter *entities.DeadLetter,
+) error {
+	const query = `
+		INSERT INTO accrual_dead_letters
+			(order_number, status_code, body, error)
+		VALUES
+			($1, $2, $3, $4)
+		RETURNING
+			id,
+			created_at
+	`
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).
+		QueryRowContext(ctx, query, letter.Order, letter.StatusCode, letter.Body, letter.Error).
+		Scan(&letter.ID, &letter.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *DeadLetterRepository) GetDeadLetters(
+	ctx context.Context,
+) ([]*entities.DeadLetter, error) {
+	const query = `
+		SELECT
+			id,
+			order_number,
+			status_code,
+			body,
+			error,
+			created_at
+		FROM
+			accrual_dead_letters
+		ORDER BY
+			created_at
+		DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]*entities.DeadLetter, 0)
+
+	for rows.Next() {
+		letter := new(entities.DeadLetter)
+		err = rows.Scan(
+			&letter.ID,
+			&letter.Order,
+			&letter.StatusCode,
+			&letter.Body,
+			&letter.Error,
+			&letter.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		letters = append(letters, letter)
+	}
+
+	defer func() {
+		if err = rows.Close(); err != nil {
+			r.logger.Errorf("close rows: %s", err)
+		}
+	}()
+
+	// Rows.Err will report the last error encountered by Rows.Scan.
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(letters) == 0 {
+		return nil, errs.ErrNotFound
+	}
+
+	return letters, nil
+}
+
+func (r *DeadLetterRepository) GetDeadLetter(
+	ctx context.Context, id int,
+) (*entities.DeadLetter, error) {
+	const query = `
+		SELECT
+			id,
+			order_number,
+			status_code,
+			body,
+			error,
+			created_at
+		FROM
+			accrual_dead_letters
+		WHERE
+			id = $1
+	`
+
+	letter := new(entities.DeadLetter)
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).
+		QueryRowContext(ctx, query, id).
+		Scan(
+			&letter.ID,
+			&letter.Order,
+			&letter.StatusCode,
+			&letter.Body,
+			&letter.Error,
+			&letter.CreatedAt,
+		)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: dead letter %d", errs.ErrNotFound, id)
+		}
+		return nil, err
+	}
+
+	return letter, nil
+}
+
+func (r *DeadLetterRepository) DeleteDeadLetter(ctx context.Context, id int) error {
+	const query = `
+		DELETE FROM
+			accrual_dead_letters
+		WHERE
+			id = $1
+	`
+
+	res, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return fmt.Errorf("%w: dead letter %d", errs.ErrNotFound, id)
+	}
+
+	return nil
+}
@@ -0,0 +1,236 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
+	"github.com/KretovDmitry/gophermart/internal/domain/repositories"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+	trmsql "github.com/avito-tech/go-transaction-manager/drivers/sql/v2"
+)
+
+type HoldRepository struct {
+	db     *sql.DB
+	getter *trmsql.CtxGetter
+	logger logger.Logger
+}
+
+func NewHoldRepository(
+	db *sql.DB, getter *trmsql.CtxGetter, logger logger.Logger,
+) (*HoldRepository, error) {
+	if db == nil {
+		return nil, errors.New("nil dependency: database")
+	}
+	if getter == nil {
+		return nil, errors.New("nil dependency: transaction getter")
+	}
+
+	return &HoldRepository{db: db, getter: getter, logger: logger}, nil
+}
+
+var _ repositories.HoldRepository = (*HoldRepository)(nil)
+
+// CreateHold saves the active hold expiring in ttl.
+func (r *HoldRepository) CreateHold(
+	ctx context.Context, hold *entities.Hold, ttl time.Duration,
+) error {
+	const query = `
+		INSERT INTO holds
+			(account_id, order_number, sum, expires_at)
+		VALUES
+			(
+				(SELECT id FROM accounts WHERE user_id = $1),
+				$2,
+				$3,
+				CURRENT_TIMESTAMP + make_interval(secs => $4)
+			)
+		RETURNING
+			id,
+			status,
+			created_at,
+			expires_at
+	`
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).
+		QueryRowContext(ctx, query, hold.UserID, hold.Order, hold.Sum, ttl.Seconds()).
+		Scan(&hold.ID, &hold.Status, &hold.CreatedAt, &hold.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LockReleasedOrder locks the user's order until the end of the transaction
+// if the order has holds and all of them are released. Otherwise ErrNotFound
+// is returned.
+func (r *HoldRepository) LockReleasedOrder(
+	ctx context.Context, id user.ID, num entities.OrderNumber,
+) error {
+	// The holds are checked after the order is locked,
+	// so that they are seen as left by the previous holder.
+	const lockQuery = `
+		SELECT
+			1
+		FROM
+			orders
+		WHERE
+			number = $1
+		AND
+			user_id = $2
+		AND
+			kind = 'WITHDRAWAL'
+		FOR UPDATE
+	`
+	const releasedQuery = `
+		SELECT
+			count(*) > 0 AND bool_and(status = 'RELEASED')
+		FROM
+			holds
+		WHERE
+			order_number = $1
+	`
+
+	db := r.getter.DefaultTrOrDB(ctx, r.db)
+
+	var locked int
+	err := db.QueryRowContext(ctx, lockQuery, num, id).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: order %q", errs.ErrNotFound, num)
+		}
+		return err
+	}
+
+	var released bool
+	if err = db.QueryRowContext(ctx, releasedQuery, num).Scan(&released); err != nil {
+		return err
+	}
+
+	if !released {
+		return fmt.Errorf("%w: released holds of order %q", errs.ErrNotFound, num)
+	}
+
+	return nil
+}
+
+// LockHold returns the hold locked until the end of the transaction.
+func (r *HoldRepository) LockHold(ctx context.Context, id int) (*entities.Hold, error) {
+	const query = `
+		SELECT
+			h.id,
+			a.user_id,
+			h.order_number,
+			h.sum,
+			h.status,
+			h.created_at,
+			h.expires_at,
+			h.expires_at <= CURRENT_TIMESTAMP
+		FROM
+			holds h
+		JOIN
+			accounts a ON a.id = h.account_id
+		WHERE
+			h.id = $1
+		FOR UPDATE OF h
+	`
+
+	hold := new(entities.Hold)
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).
+		QueryRowContext(ctx, query, id).
+		Scan(
+			&hold.ID,
+			&hold.UserID,
+			&hold.Order,
+			&hold.Sum,
+			&hold.Status,
+			&hold.CreatedAt,
+			&hold.ExpiresAt,
+			&hold.Expired,
+		)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: hold %d", errs.ErrNotFound, id)
+		}
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// ResolveHold sets the final status of the hold.
+func (r *HoldRepository) ResolveHold(
+	ctx context.Context, id int, status entities.HoldStatus,
+) error {
+	const query = `
+		UPDATE
+			holds
+		SET
+			status = $2,
+			resolved_at = CURRENT_TIMESTAMP
+		WHERE
+			id = $1
+	`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, id, status)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetExpiredHolds returns IDs of active holds past their expiry time,
+// the longest expired first.
+func (r *HoldRepository) GetExpiredHolds(ctx context.Context, limit int) ([]int, error) {
+	const query = `
+		SELECT
+			id
+		FROM
+			holds
+		WHERE
+			status = 'ACTIVE'
+		AND
+			expires_at <= CURRENT_TIMESTAMP
+		ORDER BY
+			expires_at
+		LIMIT
+			$1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0)
+
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	defer func() {
+		if err = rows.Close(); err != nil {
+			r.logger.Errorf("close rows: %s", err)
+		}
+	}()
+
+	// Rows.Err will report the last error encountered by Rows.Scan.
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
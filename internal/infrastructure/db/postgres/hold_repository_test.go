@@ -0,0 +1,59 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/infrastructure/db/postgres"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+	trmsql "github.com/avito-tech/go-transaction-manager/drivers/sql/v2"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHoldRepositoryLockReleasedOrder(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	id := testUser(t, db, "released-order")
+	other := testUser(t, db, "released-order-other")
+
+	logger, _ := logger.NewForTest()
+
+	accounts, err := postgres.NewAccountRepository(db, trmsql.DefaultCtxGetter, logger)
+	require.NoError(t, err)
+	orders, err := postgres.NewOrderRepository(db, trmsql.DefaultCtxGetter, logger)
+	require.NoError(t, err)
+	repo, err := postgres.NewHoldRepository(db, trmsql.DefaultCtxGetter, logger)
+	require.NoError(t, err)
+
+	require.NoError(t, accounts.CreateAccount(ctx, id))
+
+	// The withdrawal without holds is not reused.
+	withdrawn := testOrderNumber()
+	require.NoError(t, orders.CreateWithdrawalOrder(ctx, id, withdrawn))
+	assert.ErrorIs(t, repo.LockReleasedOrder(ctx, id, withdrawn), errs.ErrNotFound)
+
+	num := testOrderNumber()
+	require.NoError(t, orders.CreateWithdrawalOrder(ctx, id, num))
+
+	hold := func() *entities.Hold {
+		h := &entities.Hold{UserID: id, Order: num, Sum: decimal.NewFromInt(10)}
+		require.NoError(t, repo.CreateHold(ctx, h, time.Hour))
+		return h
+	}
+
+	first := hold()
+	assert.ErrorIs(t, repo.LockReleasedOrder(ctx, id, num), errs.ErrNotFound, "the hold is active")
+
+	require.NoError(t, repo.ResolveHold(ctx, first.ID, entities.HoldReleased))
+	assert.NoError(t, repo.LockReleasedOrder(ctx, id, num))
+	assert.ErrorIs(t, repo.LockReleasedOrder(ctx, other, num), errs.ErrNotFound, "orders of other users")
+
+	second := hold()
+	require.NoError(t, repo.ResolveHold(ctx, second.ID, entities.HoldCaptured))
+	assert.ErrorIs(t, repo.LockReleasedOrder(ctx, id, num), errs.ErrNotFound, "one of the holds is captured")
+}
@@ -0,0 +1,201 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
+	"github.com/KretovDmitry/gophermart/internal/domain/repositories"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+	trmsql "github.com/avito-tech/go-transaction-manager/drivers/sql/v2"
+)
+
+type IdempotencyRepository struct {
+	db     *sql.DB
+	getter *trmsql.CtxGetter
+	logger logger.Logger
+}
+
+func NewIdempotencyRepository(
+	db *sql.DB, getter *trmsql.CtxGetter, logger logger.Logger,
+) (*IdempotencyRepository, error) {
+	if db == nil {
+		return nil, errors.New("nil dependency: database")
+	}
+	if getter == nil {
+		return nil, errors.New("nil dependency: transaction getter")
+	}
+
+	return &IdempotencyRepository{db: db, getter: getter, logger: logger}, nil
+}
+
+var _ repositories.IdempotencyRepository = (*IdempotencyRepository)(nil)
+
+// CreateIdempotencyKey saves the key without the response. The expired key
+// is taken over, so is the key left without the response for longer than
+// the in-progress timeout. It returns errs.ErrAlreadyExists if the key is in use.
+func (r *IdempotencyRepository) CreateIdempotencyKey(
+	ctx context.Context, key *entities.IdempotencyKey, ttl, inProgressTimeout time.Duration,
+) error {
+	const query = `
+		INSERT INTO idempotency_keys
+			(user_id, key, request_hash)
+		VALUES
+			($1, $2, $3)
+		ON CONFLICT (user_id, key) DO UPDATE
+		SET
+			request_hash = EXCLUDED.request_hash,
+			status_code = NULL,
+			content_type = NULL,
+			body = NULL,
+			created_at = CURRENT_TIMESTAMP
+		WHERE
+			idempotency_keys.created_at < CURRENT_TIMESTAMP - make_interval(secs => $4)
+		OR
+			(
+				idempotency_keys.status_code IS NULL
+			AND
+				idempotency_keys.created_at < CURRENT_TIMESTAMP - make_interval(secs => $5)
+			)
+	`
+
+	res, err := r.getter.DefaultTrOrDB(ctx, r.db).
+		ExecContext(ctx, query, key.UserID, key.Key, key.RequestHash, ttl.Seconds(), inProgressTimeout.Seconds())
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return fmt.Errorf("%w: idempotency key %q", errs.ErrAlreadyExists, key.Key)
+	}
+
+	return nil
+}
+
+func (r *IdempotencyRepository) GetIdempotencyKey(
+	ctx context.Context, id user.ID, key string,
+) (*entities.IdempotencyKey, error) {
+	const query = `
+		SELECT
+			request_hash,
+			status_code,
+			content_type,
+			body,
+			created_at
+		FROM
+			idempotency_keys
+		WHERE
+			user_id = $1
+		AND
+			key = $2
+	`
+
+	k := &entities.IdempotencyKey{UserID: id, Key: key}
+
+	var statusCode sql.NullInt64
+	var contentType sql.NullString
+	var body []byte
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).
+		QueryRowContext(ctx, query, id, key).
+		Scan(&k.RequestHash, &statusCode, &contentType, &body, &k.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: idempotency key %q", errs.ErrNotFound, key)
+		}
+		return nil, err
+	}
+
+	if statusCode.Valid {
+		k.Response = &entities.IdempotentResponse{
+			StatusCode:  int(statusCode.Int64),
+			ContentType: contentType.String,
+			Body:        body,
+		}
+	}
+
+	return k, nil
+}
+
+// SaveIdempotentResponse saves the response unless the key already has one.
+func (r *IdempotencyRepository) SaveIdempotentResponse(
+	ctx context.Context, id user.ID, key string, res *entities.IdempotentResponse,
+) error {
+	const query = `
+		UPDATE
+			idempotency_keys
+		SET
+			status_code = $3,
+			content_type = $4,
+			body = $5
+		WHERE
+			user_id = $1
+		AND
+			key = $2
+		AND
+			status_code IS NULL
+	`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).
+		ExecContext(ctx, query, id, key, res.StatusCode, res.ContentType, res.Body)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *IdempotencyRepository) DeleteIdempotencyKey(
+	ctx context.Context, id user.ID, key string,
+) error {
+	const query = `
+		DELETE FROM
+			idempotency_keys
+		WHERE
+			user_id = $1
+		AND
+			key = $2
+	`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, id, key)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys deletes the keys older than ttl
+// and returns the number of deleted keys.
+func (r *IdempotencyRepository) DeleteExpiredIdempotencyKeys(
+	ctx context.Context, ttl time.Duration,
+) (int, error) {
+	const query = `
+		DELETE FROM
+			idempotency_keys
+		WHERE
+			created_at < CURRENT_TIMESTAMP - make_interval(secs => $1)
+	`
+
+	res, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, ttl.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), nil
+}
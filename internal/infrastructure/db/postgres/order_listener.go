@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/domain/repositories"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+	"github.com/jackc/pgx/v5"
+)
+
+// Channel notified with the number of each created order.
+const ordersCreatedChannel = "orders_created"
+
+// Delay before reconnecting the lost listener connection.
+const reconnectDelay = 5 * time.Second
+
+// OrderListener listens for the created orders
+// on a dedicated connection outside of the pool.
+type OrderListener struct {
+	dsn    string
+	logger logger.Logger
+}
+
+func NewOrderListener(dsn string, logger logger.Logger) (*OrderListener, error) {
+	if dsn == "" {
+		return nil, errors.New("empty data source name")
+	}
+
+	return &OrderListener{dsn: dsn, logger: logger}, nil
+}
+
+var _ repositories.OrderListener = (*OrderListener)(nil)
+
+// ListenCreatedOrders returns the channel of created order numbers, which
+// is closed when the context is done. The lost connection is restored.
+func (l *OrderListener) ListenCreatedOrders(ctx context.Context) <-chan entities.OrderNumber {
+	out := make(chan entities.OrderNumber)
+
+	go func() {
+		defer close(out)
+
+		for {
+			err := l.listen(ctx, out)
+			if ctx.Err() != nil {
+				return
+			}
+
+			l.logger.Errorf("listen created orders: %v", err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(reconnectDelay):
+			}
+		}
+	}()
+
+	return out
+}
+
+func (l *OrderListener) listen(ctx context.Context, out chan<- entities.OrderNumber) error {
+	conn, err := pgx.Connect(ctx, l.dsn)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer func() {
+		if err = conn.Close(context.WithoutCancel(ctx)); err != nil {
+			l.logger.Errorf("close listener connection: %s", err)
+		}
+	}()
+
+	if _, err = conn.Exec(ctx, "LISTEN "+ordersCreatedChannel); err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("wait for notification: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case out <- entities.OrderNumber(notification.Payload):
+		}
+	}
+}
@@ -4,13 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/application/params"
 	"github.com/KretovDmitry/gophermart/internal/domain/entities"
 	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
 	"github.com/KretovDmitry/gophermart/internal/domain/repositories"
 	"github.com/KretovDmitry/gophermart/pkg/logger"
 	trmsql "github.com/avito-tech/go-transaction-manager/drivers/sql/v2"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/shopspring/decimal"
 )
 
 type OrderRepository struct {
@@ -47,17 +53,18 @@ func (r *OrderRepository) CreateOrder(
 			ON CONFLICT (number) DO NOTHING
 			RETURNING user_id
 		) 
-		SELECT FALSE AS source, user_id FROM ins UNION ALL
-		SELECT TRUE AS source, c.user_id FROM input_rows 
+		SELECT FALSE AS source, user_id, 'ACCRUAL'::order_kind AS kind FROM ins UNION ALL
+		SELECT TRUE AS source, c.user_id, c.kind FROM input_rows 
 		JOIN orders c USING (number);
 	`
 
 	var alreadyExists bool
 	var userID user.ID
+	var kind string
 
 	err := r.getter.DefaultTrOrDB(ctx, r.db).
 		QueryRowContext(ctx, query, id, num).
-		Scan(&alreadyExists, &userID)
+		Scan(&alreadyExists, &userID, &kind)
 	if err != nil {
 		return err
 	}
@@ -65,6 +72,9 @@ func (r *OrderRepository) CreateOrder(
 	switch {
 	case !alreadyExists && userID == id:
 		return nil
+	// The number is already spent on the withdrawal.
+	case alreadyExists && kind != "ACCRUAL":
+		return errs.ErrDataConflict
 	case alreadyExists && userID == id:
 		return errs.ErrAlreadyExists
 	case alreadyExists && userID != id:
@@ -74,8 +84,162 @@ func (r *OrderRepository) CreateOrder(
 	return nil
 }
 
-func (r *OrderRepository) GetOrdersByUserID(
-	ctx context.Context, id user.ID,
+// CreateOrders creates user's orders in one round trip. Each order gets
+// the same result as CreateOrder would return: nil if it is created,
+// errs.ErrAlreadyExists if it is already uploaded by the user and
+// errs.ErrDataConflict if the number is taken by someone else.
+func (r *OrderRepository) CreateOrders(
+	ctx context.Context, id user.ID, nums []entities.OrderNumber,
+) (map[entities.OrderNumber]error, error) {
+	// The outer query doesn't see the rows inserted by
+	// the same statement, so joined orders existed before.
+	const query = `
+		WITH input_rows(number) AS (
+			SELECT DISTINCT unnest($2::text[])
+		),
+		ins AS (
+			INSERT INTO orders (user_id, number)
+			SELECT $1, number FROM input_rows
+			ON CONFLICT (number) DO NOTHING
+			RETURNING number
+		)
+		SELECT
+			i.number,
+			ins.number IS NOT NULL AS inserted,
+			c.user_id,
+			c.kind
+		FROM
+			input_rows i
+		LEFT JOIN
+			ins USING (number)
+		LEFT JOIN
+			orders c USING (number)
+	`
+
+	numbers := make([]string, len(nums))
+	for i, num := range nums {
+		numbers[i] = string(num)
+	}
+
+	rows, err := r.getter.DefaultTrOrDB(ctx, r.db).QueryContext(ctx, query, id, numbers)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[entities.OrderNumber]error, len(nums))
+
+	for rows.Next() {
+		var num entities.OrderNumber
+		var inserted bool
+		var userID sql.NullInt64
+		var kind sql.NullString
+
+		if err = rows.Scan(&num, &inserted, &userID, &kind); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case inserted:
+			results[num] = nil
+		case userID.Valid && user.ID(userID.Int64) == id && kind.String == "ACCRUAL":
+			results[num] = errs.ErrAlreadyExists
+		// Taken by someone else, maybe concurrently.
+		default:
+			results[num] = errs.ErrDataConflict
+		}
+	}
+
+	defer func() {
+		if err = rows.Close(); err != nil {
+			r.logger.Errorf("close rows: %s", err)
+		}
+	}()
+
+	// Rows.Err will report the last error encountered by Rows.Scan.
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// CreateWithdrawalOrder registers the number of the order paid with
+// the withdrawal. Such orders are never checked in the accrual system.
+func (r *OrderRepository) CreateWithdrawalOrder(
+	ctx context.Context, id user.ID, num entities.OrderNumber,
+) error {
+	const query = `
+		INSERT INTO orders
+			(user_id, number, kind)
+		VALUES
+			($1, $2, 'WITHDRAWAL')
+		ON CONFLICT (number) DO NOTHING
+	`
+
+	res, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, id, num)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	// Order numbers never repeat.
+	if n == 0 {
+		return fmt.Errorf("%w: order %q already exists", errs.ErrDataConflict, num)
+	}
+
+	return nil
+}
+
+// GetOrderByNumber returns the accrual order.
+func (r *OrderRepository) GetOrderByNumber(
+	ctx context.Context, num entities.OrderNumber,
+) (*entities.Order, error) {
+	const query = `
+		SELECT
+			id,
+			user_id,
+			number,
+			status,
+			accrual,
+			uploadet_at
+		FROM
+			orders
+		WHERE
+			number = $1
+		AND
+			kind = 'ACCRUAL'
+	`
+
+	order := new(entities.Order)
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).
+		QueryRowContext(ctx, query, num).
+		Scan(
+			&order.ID,
+			&order.UserID,
+			&order.Number,
+			&order.Status,
+			&order.Accrual,
+			&order.UploadetAt,
+		)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: order %q", errs.ErrNotFound, num)
+		}
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// ListOrders returns the page of user's orders matching the filter
+// sorted by upload time. It returns errs.ErrNotFound if there are none.
+func (r *OrderRepository) ListOrders(
+	ctx context.Context, p *params.ListOrders,
 ) ([]*entities.Order, error) {
 	const query = `
 		SELECT
@@ -89,17 +253,54 @@ func (r *OrderRepository) GetOrdersByUserID(
 			orders
 		WHERE
 			user_id = $1
+		AND
+			kind = 'ACCRUAL'
+		AND
+			(cardinality($2::text[]) = 0 OR status::text = ANY($2))
+		AND
+			($3::timestamp IS NULL OR uploadet_at >= $3)
+		AND
+			($4::timestamp IS NULL OR uploadet_at < $4)
+		AND
+			%s
 		ORDER BY
-			uploadet_at
-		DESC
+			%s
+		LIMIT
+			$7
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, id)
+	// Keyset pagination: the page starts right after the cursor.
+	after, order := `($5::timestamp IS NULL OR (uploadet_at, id) < ($5, $6))`, `uploadet_at DESC, id DESC`
+	if p.Sort == params.SortAsc {
+		after, order = `($5::timestamp IS NULL OR (uploadet_at, id) > ($5, $6))`, `uploadet_at, id`
+	}
+
+	statuses := make([]string, len(p.Statuses))
+	for i, status := range p.Statuses {
+		statuses[i] = string(status)
+	}
+
+	var afterAt sql.NullTime
+	var afterID int
+	if p.After != nil {
+		afterAt = sql.NullTime{Time: p.After.At, Valid: true}
+		afterID = p.After.ID
+	}
+
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(query, after, order),
+		p.UserID,
+		statuses,
+		sql.NullTime{Time: p.From, Valid: !p.From.IsZero()},
+		sql.NullTime{Time: p.To, Valid: !p.To.IsZero()},
+		afterAt,
+		afterID,
+		sql.NullInt64{Int64: int64(p.Limit), Valid: p.Limit > 0},
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	orders := make([]*entities.Order, 0)
+	orders := make([]*entities.Order, 0, p.Limit)
 
 	for rows.Next() {
 		order := new(entities.Order)
@@ -136,35 +337,63 @@ func (r *OrderRepository) GetOrdersByUserID(
 	return orders, nil
 }
 
-func (r *OrderRepository) GetUnprocessedOrders(
-	ctx context.Context, limit, offset int,
-) ([]*entities.Order, error) {
+// ClaimUnprocessedOrders leases unprocessed orders due to be checked with ID
+// greater than the cursor to the owner for the given duration. Orders leased by someone
+// else are skipped until the lease expires, so the crashed owner's orders
+// are reclaimed automatically. It returns the cursor to continue the scan
+// from, which is zero when the end of the table is reached.
+func (r *OrderRepository) ClaimUnprocessedOrders(
+	ctx context.Context, owner string, lease time.Duration, after, limit int,
+) ([]*entities.Order, int, error) {
 	const query = `
-		SELECT
-			id,
-			user_id,
-			number,
-			status,
-			accrual,
-			uploadet_at
+		WITH claimable AS (
+			SELECT
+				id
+			FROM
+				orders
+			WHERE
+				id > $3
+			AND
+				kind = 'ACCRUAL'
+			AND
+				status IN ('NEW', 'PROCESSING')
+			AND
+				next_check_at <= CURRENT_TIMESTAMP
+			AND
+				(claimed_until IS NULL OR claimed_until < CURRENT_TIMESTAMP)
+			ORDER BY
+				id
+			LIMIT
+				$4
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE
+			orders o
+		SET
+			claimed_by = $1,
+			claimed_until = CURRENT_TIMESTAMP + make_interval(secs => $2)
 		FROM
-			orders
+			claimable
 		WHERE
-			status > 'PROCESSED'
-		ORDER BY
-			id
-		LIMIT
-			$1
-		OFFSET
-			$2
+			o.id = claimable.id
+		RETURNING
+			o.id,
+			o.user_id,
+			o.number,
+			o.status,
+			o.accrual,
+			o.uploadet_at,
+			o.attempts
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	rows, err := r.getter.DefaultTrOrDB(ctx, r.db).
+		QueryContext(ctx, query, owner, lease.Seconds(), after, limit)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	orders := make([]*entities.Order, 0, limit)
+	next := 0
 
 	for rows.Next() {
 		order := new(entities.Order)
@@ -175,12 +404,14 @@ func (r *OrderRepository) GetUnprocessedOrders(
 			&order.Status,
 			&order.Accrual,
 			&order.UploadetAt,
+			&order.Attempts,
 		)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		orders = append(orders, order)
+		next = max(next, order.ID)
 	}
 
 	defer func() {
@@ -191,16 +422,265 @@ func (r *OrderRepository) GetUnprocessedOrders(
 
 	// Rows.Err will report the last error encountered by Rows.Scan.
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	if len(orders) == 0 {
-		return nil, errs.ErrNotFound
+		return nil, 0, errs.ErrNotFound
 	}
 
-	return orders, nil
+	// Less than limit means there is nothing left after the last order.
+	if len(orders) < limit {
+		next = 0
+	}
+
+	return orders, next, nil
+}
+
+// ClaimOrder leases the unprocessed order to the owner for the given duration
+// unless it is already leased by someone else.
+func (r *OrderRepository) ClaimOrder(
+	ctx context.Context, owner string, lease time.Duration, num entities.OrderNumber,
+) (*entities.Order, error) {
+	const query = `
+		WITH claimable AS (
+			SELECT
+				id
+			FROM
+				orders
+			WHERE
+				number = $3
+			AND
+				kind = 'ACCRUAL'
+			AND
+				status IN ('NEW', 'PROCESSING')
+			AND
+				(claimed_until IS NULL OR claimed_until < CURRENT_TIMESTAMP)
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE
+			orders o
+		SET
+			claimed_by = $1,
+			claimed_until = CURRENT_TIMESTAMP + make_interval(secs => $2)
+		FROM
+			claimable
+		WHERE
+			o.id = claimable.id
+		RETURNING
+			o.id,
+			o.user_id,
+			o.number,
+			o.status,
+			o.accrual,
+			o.uploadet_at,
+			o.attempts
+	`
+
+	order := new(entities.Order)
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).
+		QueryRowContext(ctx, query, owner, lease.Seconds(), num).
+		Scan(
+			&order.ID,
+			&order.UserID,
+			&order.Number,
+			&order.Status,
+			&order.Accrual,
+			&order.UploadetAt,
+			&order.Attempts,
+		)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// RescheduleOrder drops the owner's lease on the order, counts the attempt
+// to check it and postpones the next check for the given delay.
+func (r *OrderRepository) RescheduleOrder(
+	ctx context.Context, num entities.OrderNumber, owner string, delay time.Duration, lastErr string,
+) error {
+	const query = `
+		UPDATE
+			orders
+		SET
+			claimed_by = NULL,
+			claimed_until = NULL,
+			attempts = attempts + 1,
+			last_error = NULLIF($4, ''),
+			next_check_at = CURRENT_TIMESTAMP + make_interval(secs => $3)
+		WHERE
+			number = $1
+		AND
+			claimed_by = $2
+	`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).
+		ExecContext(ctx, query, num, owner, delay.Seconds(), lastErr)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ReleaseOrder drops the owner's lease on the order.
+func (r *OrderRepository) ReleaseOrder(
+	ctx context.Context, num entities.OrderNumber, owner string,
+) error {
+	const query = `
+		UPDATE
+			orders
+		SET
+			claimed_by = NULL,
+			claimed_until = NULL
+		WHERE
+			number = $1
+		AND
+			claimed_by = $2
+	`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, num, owner)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeferOrder drops the owner's lease on the order and postpones the next
+// check for the given delay without counting it as an attempt.
+func (r *OrderRepository) DeferOrder(
+	ctx context.Context, num entities.OrderNumber, owner string, delay time.Duration,
+) error {
+	const query = `
+		UPDATE
+			orders
+		SET
+			claimed_by = NULL,
+			claimed_until = NULL,
+			next_check_at = CURRENT_TIMESTAMP + make_interval(secs => $3)
+		WHERE
+			number = $1
+		AND
+			claimed_by = $2
+	`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).
+		ExecContext(ctx, query, num, owner, delay.Seconds())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ParkOrder drops the owner's lease on the order, counts the attempt to
+// check it and stops checking it until the order is unparked.
+func (r *OrderRepository) ParkOrder(
+	ctx context.Context, num entities.OrderNumber, owner string, lastErr string,
+) error {
+	const query = `
+		UPDATE
+			orders
+		SET
+			claimed_by = NULL,
+			claimed_until = NULL,
+			attempts = attempts + 1,
+			last_error = NULLIF($3, ''),
+			next_check_at = 'infinity'
+		WHERE
+			number = $1
+		AND
+			claimed_by = $2
+	`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).
+		ExecContext(ctx, query, num, owner, lastErr)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UnparkOrder makes the parked order due to be checked right away.
+func (r *OrderRepository) UnparkOrder(
+	ctx context.Context, num entities.OrderNumber,
+) error {
+	const query = `
+		UPDATE
+			orders
+		SET
+			attempts = 0,
+			last_error = NULL,
+			next_check_at = CURRENT_TIMESTAMP
+		WHERE
+			number = $1
+		AND
+			next_check_at = 'infinity'
+	`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, num)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LockOrder returns the accrual order locking it until the end of the transaction.
+func (r *OrderRepository) LockOrder(
+	ctx context.Context, num entities.OrderNumber,
+) (*entities.Order, error) {
+	const query = `
+		SELECT
+			id,
+			user_id,
+			number,
+			status,
+			accrual,
+			uploadet_at,
+			attempts
+		FROM
+			orders
+		WHERE
+			number = $1
+		AND
+			kind = 'ACCRUAL'
+		FOR UPDATE
+	`
+
+	order := new(entities.Order)
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).
+		QueryRowContext(ctx, query, num).
+		Scan(
+			&order.ID,
+			&order.UserID,
+			&order.Number,
+			&order.Status,
+			&order.Accrual,
+			&order.UploadetAt,
+			&order.Attempts,
+		)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: order %q", errs.ErrNotFound, num)
+		}
+		return nil, err
+	}
+
+	return order, nil
 }
 
+// UpdateOrder sets the status and the accrual of the order. The database
+// rejects illegal status transitions and changes of final orders.
 func (r *OrderRepository) UpdateOrder(
 	ctx context.Context, info *entities.UpdateOrderInfo,
 ) (user.ID, error) {
@@ -222,8 +702,239 @@ func (r *OrderRepository) UpdateOrder(
 		QueryRowContext(ctx, query, info.Status, info.Accrual, info.Number).
 		Scan(&userID)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return userID, fmt.Errorf("%w: order %q", errs.ErrNotFound, info.Number)
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == pgerrcode.CheckViolation {
+				return userID, fmt.Errorf("%w: %s", errs.ErrInvalidTransition, pgErr.Message)
+			}
+		}
 		return userID, err
 	}
 
 	return userID, nil
 }
+
+// CancelOrder deletes the order freeing its number
+// and keeps the record of the cancellation.
+func (r *OrderRepository) CancelOrder(ctx context.Context, order *entities.Order) error {
+	const query = `
+		WITH cancelled AS (
+			DELETE FROM
+				orders
+			WHERE
+				id = $1
+			AND
+				kind = 'ACCRUAL'
+			RETURNING
+				id,
+				user_id,
+				number,
+				uploadet_at
+		)
+		INSERT INTO order_cancellations
+			(order_id, user_id, number, uploadet_at)
+		SELECT
+			id,
+			user_id,
+			number,
+			uploadet_at
+		FROM
+			cancelled
+	`
+
+	res, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, order.ID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return fmt.Errorf("%w: order %q", errs.ErrNotFound, order.Number)
+	}
+
+	return nil
+}
+
+// SaveStatusChange appends the status the order moved to to its history.
+func (r *OrderRepository) SaveStatusChange(
+	ctx context.Context, orderID int, status entities.OrderStatus, accrual decimal.Decimal,
+) error {
+	const query = `
+		INSERT INTO order_status_history
+			(order_id, status, accrual)
+		VALUES
+			($1, $2, $3)
+	`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).
+		ExecContext(ctx, query, orderID, status, accrual)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetOrderHistory returns the timeline of the user's order starting with
+// its upload. It returns errs.ErrNotFound if the user has no such order.
+func (r *OrderRepository) GetOrderHistory(
+	ctx context.Context, id user.ID, num entities.OrderNumber,
+) ([]*entities.OrderStatusChange, error) {
+	const query = `
+		SELECT
+			status,
+			accrual,
+			reason,
+			changed_at
+		FROM (
+			SELECT
+				0 AS id,
+				'NEW'::order_status AS status,
+				0::numeric AS accrual,
+				'' AS reason,
+				uploadet_at AS changed_at
+			FROM
+				orders
+			WHERE
+				number = $1
+			AND
+				user_id = $2
+			AND
+				kind = 'ACCRUAL'
+			UNION ALL
+			SELECT
+				h.id,
+				h.status,
+				h.accrual,
+				COALESCE(h.reason, ''),
+				h.changed_at
+			FROM
+				order_status_history h
+			JOIN
+				orders o ON o.id = h.order_id
+			WHERE
+				o.number = $1
+			AND
+				o.user_id = $2
+			AND
+				o.kind = 'ACCRUAL'
+		) timeline
+		ORDER BY
+			changed_at,
+			id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, num, id)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]*entities.OrderStatusChange, 0)
+
+	for rows.Next() {
+		change := new(entities.OrderStatusChange)
+		err = rows.Scan(
+			&change.Status,
+			&change.Accrual,
+			&change.Reason,
+			&change.ChangedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		history = append(history, change)
+	}
+
+	defer func() {
+		if err = rows.Close(); err != nil {
+			r.logger.Errorf("close rows: %s", err)
+		}
+	}()
+
+	// Rows.Err will report the last error encountered by Rows.Scan.
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(history) == 0 {
+		return nil, errs.ErrNotFound
+	}
+
+	return history, nil
+}
+
+// ExpireOrders invalidates accrual orders staying in the status longer than
+// the max age and records the reason in their history. The age is measured
+// from the latest change to the status, or from the upload if the history
+// has none. Orders leased
+// to the accrual service at the moment are left until the next time.
+// It returns the number of expired orders.
+func (r *OrderRepository) ExpireOrders(
+	ctx context.Context, status entities.OrderStatus, maxAge time.Duration, reason string,
+) (int, error) {
+	const query = `
+		WITH expired AS (
+			UPDATE
+				orders
+			SET
+				status = 'INVALID'
+			WHERE
+				id IN (
+					SELECT
+						id
+					FROM
+						orders
+					WHERE
+						kind = 'ACCRUAL'
+					AND
+						status = $1
+					AND
+						COALESCE(
+							(
+								SELECT
+									max(h.changed_at)
+								FROM
+									order_status_history h
+								WHERE
+									h.order_id = orders.id
+								AND
+									h.status = $1
+							),
+							uploadet_at
+						) < CURRENT_TIMESTAMP - make_interval(secs => $2)
+					AND
+						(claimed_until IS NULL OR claimed_until < CURRENT_TIMESTAMP)
+					FOR UPDATE SKIP LOCKED
+				)
+			RETURNING
+				id
+		)
+		INSERT INTO order_status_history
+			(order_id, status, reason)
+		SELECT
+			id, 'INVALID', $3
+		FROM
+			expired
+	`
+
+	res, err := r.getter.DefaultTrOrDB(ctx, r.db).
+		ExecContext(ctx, query, status, maxAge.Seconds(), reason)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), nil
+}
@@ -0,0 +1,373 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/application/params"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
+	"github.com/KretovDmitry/gophermart/internal/infrastructure/db/postgres"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+	trmsql "github.com/avito-tech/go-transaction-manager/drivers/sql/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestOrderRepository(t *testing.T, db *sql.DB) *postgres.OrderRepository {
+	t.Helper()
+
+	logger, _ := logger.NewForTest()
+
+	repo, err := postgres.NewOrderRepository(db, trmsql.DefaultCtxGetter, logger)
+	require.NoError(t, err)
+
+	return repo
+}
+
+// createTestOrders uploads n orders of the user and returns them in the order of upload.
+func createTestOrders(t *testing.T, repo *postgres.OrderRepository, id user.ID, n int) []*entities.Order {
+	t.Helper()
+
+	ctx := context.Background()
+
+	orders := make([]*entities.Order, n)
+	for i := range orders {
+		num := testOrderNumber()
+		require.NoError(t, repo.CreateOrder(ctx, id, num))
+
+		order, err := repo.GetOrderByNumber(ctx, num)
+		require.NoError(t, err)
+		orders[i] = order
+	}
+
+	return orders
+}
+
+type testLease struct {
+	owner    sql.NullString
+	attempts int
+}
+
+func getTestLease(t *testing.T, db *sql.DB, num entities.OrderNumber) testLease {
+	t.Helper()
+
+	var l testLease
+	err := db.QueryRow(`SELECT claimed_by, attempts FROM orders WHERE number = $1`, num).
+		Scan(&l.owner, &l.attempts)
+	require.NoError(t, err)
+
+	return l
+}
+
+func TestOrderRepositoryClaimUnprocessedOrdersConcurrently(t *testing.T) {
+	const (
+		claimers = 2
+		n        = 20
+		limit    = 3
+	)
+
+	db := testDB(t)
+	repo := newTestOrderRepository(t, db)
+	id := testUser(t, db, "claim-concurrently")
+
+	orders := createTestOrders(t, repo, id, n)
+
+	// Only the orders of the test are scanned.
+	after := orders[0].ID - 1
+	mine := make(map[int]bool, n)
+	for _, order := range orders {
+		mine[order.ID] = true
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		claimed = make(map[int]string)
+	)
+
+	start := make(chan struct{})
+
+	for i := 0; i < claimers; i++ {
+		owner := "claimer-" + string(rune('a'+i))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			for cursor := after; ; {
+				batch, next, err := repo.ClaimUnprocessedOrders(context.Background(), owner, time.Minute, cursor, limit)
+				if errors.Is(err, errs.ErrNotFound) {
+					return
+				}
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				mu.Lock()
+				for _, order := range batch {
+					if !mine[order.ID] {
+						continue
+					}
+					if prev, ok := claimed[order.ID]; ok {
+						t.Errorf("order %d claimed by %s and %s", order.ID, prev, owner)
+					}
+					claimed[order.ID] = owner
+				}
+				mu.Unlock()
+
+				if next == 0 {
+					return
+				}
+				cursor = next
+			}
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	assert.Len(t, claimed, n, "every order is claimed once")
+
+	// Leased orders are skipped by everyone until the lease expires.
+	_, _, err := repo.ClaimUnprocessedOrders(context.Background(), "late", time.Minute, after, n)
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+}
+
+func TestOrderRepositoryLease(t *testing.T) {
+	db := testDB(t)
+	repo := newTestOrderRepository(t, db)
+	id := testUser(t, db, "lease")
+	ctx := context.Background()
+
+	order := createTestOrders(t, repo, id, 1)[0]
+	num := order.Number
+
+	_, err := repo.ClaimOrder(ctx, "crashed", time.Millisecond, num)
+	require.NoError(t, err)
+
+	// The lease of the crashed owner expires and the order is taken over.
+	time.Sleep(10 * time.Millisecond)
+
+	_, err = repo.ClaimOrder(ctx, "owner", time.Minute, num)
+	require.NoError(t, err)
+
+	_, err = repo.ClaimOrder(ctx, "other", time.Minute, num)
+	assert.ErrorIs(t, err, errs.ErrNotFound, "active lease is not taken over")
+
+	// The former owner can't touch the order any more.
+	require.NoError(t, repo.RescheduleOrder(ctx, num, "crashed", time.Hour, "late"))
+	require.NoError(t, repo.ReleaseOrder(ctx, num, "crashed"))
+	assert.Equal(t, testLease{owner: sql.NullString{String: "owner", Valid: true}}, getTestLease(t, db, num))
+
+	// Released order is claimed again at once, attempts are not counted.
+	require.NoError(t, repo.ReleaseOrder(ctx, num, "owner"))
+	assert.Equal(t, testLease{}, getTestLease(t, db, num))
+
+	_, err = repo.ClaimOrder(ctx, "owner", time.Minute, num)
+	require.NoError(t, err)
+
+	// Rescheduled order counts the attempt and is not due until the delay passes.
+	require.NoError(t, repo.RescheduleOrder(ctx, num, "owner", time.Hour, "not registered"))
+	assert.Equal(t, testLease{attempts: 1}, getTestLease(t, db, num))
+
+	_, _, err = repo.ClaimUnprocessedOrders(ctx, "owner", time.Minute, order.ID-1, 1)
+	assert.ErrorIs(t, err, errs.ErrNotFound, "rescheduled order is not due")
+}
+
+func TestOrderRepositoryClaimUnprocessedOrdersCursor(t *testing.T) {
+	db := testDB(t)
+	repo := newTestOrderRepository(t, db)
+	id := testUser(t, db, "claim-cursor")
+	ctx := context.Background()
+
+	orders := createTestOrders(t, repo, id, 5)
+
+	var ids []int
+	batches := 0
+	for cursor := orders[0].ID - 1; ; {
+		batch, next, err := repo.ClaimUnprocessedOrders(ctx, "owner", time.Minute, cursor, 2)
+		require.NoError(t, err)
+		batches++
+
+		for _, order := range batch {
+			ids = append(ids, order.ID)
+		}
+
+		if next == 0 {
+			break
+		}
+		assert.Equal(t, batch[len(batch)-1].ID, next, "scan continues after the last claimed order")
+		cursor = next
+	}
+
+	want := make([]int, len(orders))
+	for i, order := range orders {
+		want[i] = order.ID
+	}
+	assert.Equal(t, want, ids, "orders are claimed by ID")
+	assert.Equal(t, 3, batches, "the short batch ends the scan")
+}
+
+func TestOrderRepositoryListOrdersPages(t *testing.T) {
+	db := testDB(t)
+	repo := newTestOrderRepository(t, db)
+	id := testUser(t, db, "list-pages")
+	ctx := context.Background()
+
+	orders := createTestOrders(t, repo, id, 5)
+
+	// Uploaded at the same time, ID breaks ties.
+	_, err := db.ExecContext(ctx,
+		`UPDATE orders SET uploadet_at = date_trunc('second', CURRENT_TIMESTAMP) WHERE user_id = $1`, id)
+	require.NoError(t, err)
+
+	ids := make([]int, len(orders))
+	for i, order := range orders {
+		ids[i] = order.ID
+	}
+	desc := slices.Clone(ids)
+	slices.Reverse(desc)
+
+	for _, tt := range []struct {
+		sort params.Sort
+		want []int
+	}{
+		{params.SortAsc, ids},
+		{params.SortDesc, desc},
+	} {
+		t.Run(string(tt.sort), func(t *testing.T) {
+			p := &params.ListOrders{UserID: id, Limit: 2, Sort: tt.sort}
+
+			var got []int
+			for {
+				page, err := repo.ListOrders(ctx, p)
+				if errors.Is(err, errs.ErrNotFound) {
+					break
+				}
+				require.NoError(t, err)
+				require.LessOrEqual(t, len(page), p.Limit)
+
+				for _, order := range page {
+					got = append(got, order.ID)
+				}
+
+				last := page[len(page)-1]
+				p.After = &params.Cursor{At: last.UploadetAt, ID: last.ID}
+			}
+
+			assert.Equal(t, tt.want, got, "every order is listed once across pages")
+		})
+	}
+
+	// Zero limit returns all the orders.
+	page, err := repo.ListOrders(ctx, &params.ListOrders{UserID: id})
+	require.NoError(t, err)
+	assert.Len(t, page, len(orders))
+}
+
+func TestOrderRepositoryExpireOrders(t *testing.T) {
+	// Long enough not to expire orders of anyone but the test.
+	const maxAge = 100 * time.Hour
+
+	db := testDB(t)
+	repo := newTestOrderRepository(t, db)
+	id := testUser(t, db, "expire")
+	ctx := context.Background()
+
+	orders := createTestOrders(t, repo, id, 5)
+	staleNew, leased, freshProcessing, staleProcessing, processingWithoutHistory :=
+		orders[0], orders[1], orders[2], orders[3], orders[4]
+
+	// All the orders are uploaded long ago.
+	_, err := db.ExecContext(ctx,
+		`UPDATE orders SET uploadet_at = CURRENT_TIMESTAMP - make_interval(hours => 200) WHERE user_id = $1`, id)
+	require.NoError(t, err)
+
+	_, err = db.ExecContext(ctx,
+		`UPDATE orders SET claimed_by = 'owner', claimed_until = CURRENT_TIMESTAMP + make_interval(mins => 1) WHERE id = $1`,
+		leased.ID)
+	require.NoError(t, err)
+
+	// Age of processing orders is measured from the moment they entered the status.
+	for order, hoursAgo := range map[*entities.Order]int{
+		freshProcessing:          1,
+		staleProcessing:          150,
+		processingWithoutHistory: -1,
+	} {
+		_, err = db.ExecContext(ctx, `UPDATE orders SET status = 'PROCESSING' WHERE id = $1`, order.ID)
+		require.NoError(t, err)
+
+		if hoursAgo < 0 {
+			continue
+		}
+		_, err = db.ExecContext(ctx, `
+			INSERT INTO order_status_history (order_id, status, changed_at)
+			VALUES ($1, 'PROCESSING', CURRENT_TIMESTAMP - make_interval(hours => $2))`,
+			order.ID, hoursAgo)
+		require.NoError(t, err)
+	}
+
+	n, err := repo.ExpireOrders(ctx, entities.NEW, maxAge, "stale new")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, n, 1)
+
+	n, err = repo.ExpireOrders(ctx, entities.PROCESSING, maxAge, "stale processing")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, n, 2)
+
+	want := map[*entities.Order]entities.OrderStatus{
+		staleNew:                 entities.INVALID,
+		leased:                   entities.NEW,
+		freshProcessing:          entities.PROCESSING,
+		staleProcessing:          entities.INVALID,
+		processingWithoutHistory: entities.INVALID,
+	}
+	for order, status := range want {
+		got, err := repo.GetOrderByNumber(ctx, order.Number)
+		require.NoError(t, err)
+		assert.Equal(t, status, got.Status, order.Number)
+	}
+
+	history, err := repo.GetOrderHistory(ctx, id, staleProcessing.Number)
+	require.NoError(t, err)
+	last := history[len(history)-1]
+	assert.Equal(t, entities.INVALID, last.Status)
+	assert.Equal(t, "stale processing", last.Reason)
+}
+
+func TestOrderRepositoryCancelOrder(t *testing.T) {
+	db := testDB(t)
+	repo := newTestOrderRepository(t, db)
+	id := testUser(t, db, "cancel")
+	other := testUser(t, db, "cancel-other")
+	ctx := context.Background()
+
+	order := createTestOrders(t, repo, id, 1)[0]
+
+	locked, err := repo.LockOrder(ctx, order.Number)
+	require.NoError(t, err)
+	require.NoError(t, repo.CancelOrder(ctx, locked))
+
+	_, err = repo.GetOrderByNumber(ctx, order.Number)
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+	assert.ErrorIs(t, repo.CancelOrder(ctx, locked), errs.ErrNotFound, "cancelled once")
+
+	var cancelled int
+	err = db.QueryRowContext(ctx,
+		`SELECT count(*) FROM order_cancellations WHERE order_id = $1 AND user_id = $2 AND number = $3`,
+		order.ID, id, order.Number,
+	).Scan(&cancelled)
+	require.NoError(t, err)
+	assert.Equal(t, 1, cancelled)
+
+	// The number is free for the real owner.
+	require.NoError(t, repo.CreateOrder(ctx, other, order.Number))
+}
@@ -1,10 +1,13 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/KretovDmitry/gophermart/internal/application/errs"
 	"github.com/KretovDmitry/gophermart/internal/application/interfaces"
@@ -25,8 +28,10 @@ type AccountController struct {
 }
 
 // NewAccountController registers http.Handlers with additional options.
+// Withdraw middlewares wrap the withdrawal handler only.
 func NewAccountController(
 	service interfaces.AccountService, logger logger.Logger, options ChiServerOptions,
+	withdrawMiddlewares ...MiddlewareFunc,
 ) {
 	r := options.BaseRouter
 
@@ -44,8 +49,17 @@ func NewAccountController(
 			r.Use(middleware)
 		}
 		r.Get(options.BaseURL+"/balance", c.GetBalance)
-		r.Post(options.BaseURL+"/balance/withdraw", c.Withdraw)
+		r.Group(func(r chi.Router) {
+			for _, middleware := range withdrawMiddlewares {
+				r.Use(middleware)
+			}
+			r.Post(options.BaseURL+"/balance/withdraw", c.Withdraw)
+		})
 		r.Get(options.BaseURL+"/withdrawals", c.GetWithdrawals)
+		r.Get(options.BaseURL+"/statement", c.GetStatement)
+		r.Post(options.BaseURL+"/balance/holds", c.CreateHold)
+		r.Post(options.BaseURL+"/balance/holds/{id}/capture", c.CaptureHold)
+		r.Post(options.BaseURL+"/balance/holds/{id}/release", c.ReleaseHold)
 	})
 }
 
@@ -58,6 +72,13 @@ func (c *AccountController) GetBalance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Get the requested format of amounts.
+	format, err := parseAmountFormat(r)
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
 	// Get user's account.
 	account, err := c.service.GetAccount(r.Context(), user.ID)
 	if err != nil {
@@ -66,7 +87,7 @@ func (c *AccountController) GetBalance(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create response payload.
-	response := response.NewGetBalance(account)
+	response := response.NewGetBalance(account, format)
 
 	w.Header().Set("Content-Type", "application/json")
 
@@ -137,6 +158,13 @@ func (c *AccountController) GetWithdrawals(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Get the requested format of amounts.
+	format, err := parseAmountFormat(r)
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
 	// Get all withdrawals made by the user.
 	withdrawals, err := c.service.GetWithdrawals(r.Context(), user.ID)
 	if err != nil {
@@ -147,7 +175,54 @@ func (c *AccountController) GetWithdrawals(w http.ResponseWriter, r *http.Reques
 	// Convert entities to handler response representation.
 	res := make([]*response.GetWithdrawals, len(withdrawals))
 	for i, w := range withdrawals {
-		res[i] = response.NewGetWithdrawals(w)
+		res[i] = response.NewGetWithdrawals(w, format)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// Encode them. Status 200 OK.
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+}
+
+// Get user's accruals and withdrawals with the running balance (GET /api/user/statement HTTP/1.1).
+func (c *AccountController) GetStatement(w http.ResponseWriter, r *http.Request) {
+	// Get user from context.
+	user, found := user.FromContext(r.Context())
+	if !found {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// Parse filters and pagination. All the operations are returned by default.
+	params, err := parseStatement(r, user.ID)
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Get the requested format of amounts.
+	format, err := parseAmountFormat(r)
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Get the page of the statement for the user.
+	entries, next, err := c.service.GetStatement(r.Context(), params)
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	setNextLink(w, r, next)
+
+	// Convert entities to handler response representation.
+	res := make([]*response.GetStatement, len(entries))
+	for i, e := range entries {
+		res[i] = response.NewGetStatement(e, format)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -159,6 +234,153 @@ func (c *AccountController) GetWithdrawals(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+func parseStatement(r *http.Request, id user.ID) (*params.Statement, error) {
+	q := r.URL.Query()
+
+	p := &params.Statement{UserID: id}
+
+	var err error
+	if p.Limit, err = parseLimit(q); err != nil {
+		return nil, err
+	}
+	if p.After, err = parseCursor(q); err != nil {
+		return nil, err
+	}
+	if p.From, err = parseTime(q, "from"); err != nil {
+		return nil, err
+	}
+	if p.To, err = parseTime(q, "to"); err != nil {
+		return nil, err
+	}
+	if p.Sort, err = parseSort(q); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Hold funds for the new order (POST /api/user/balance/holds HTTP/1.1).
+func (c *AccountController) CreateHold(w http.ResponseWriter, r *http.Request) {
+	// Check content type.
+	if !header.IsApplicationJSONContentType(r) {
+		c.ErrorHandlerFunc(w, r, fmt.Errorf("%w: invalid content type", errs.ErrInvalidRequest))
+		return
+	}
+
+	// Read, decode and close request body.
+	defer r.Body.Close()
+
+	var payload request.CreateHold
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		c.ErrorHandlerFunc(w, r, checkJSONDecodeError(err))
+		return
+	}
+
+	// Create selfvalidating order number entity.
+	orderNumber, err := entities.NewOrderNumber(payload.Order)
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Check if sum and ttl are meaningful.
+	if payload.Sum.LessThanOrEqual(decimal.NewFromInt(0)) {
+		c.ErrorHandlerFunc(w, r, fmt.Errorf("%w: invalid sum", errs.ErrInvalidRequest))
+		return
+	}
+	if payload.TTL < 0 {
+		c.ErrorHandlerFunc(w, r, fmt.Errorf("%w: invalid ttl", errs.ErrInvalidRequest))
+		return
+	}
+
+	// Get user from context.
+	u, found := user.FromContext(r.Context())
+	if !found {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// Get the requested format of amounts.
+	format, err := parseAmountFormat(r)
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Hold funds.
+	hold, err := c.service.CreateHold(r.Context(), &params.Hold{
+		UserID: u.ID,
+		Order:  orderNumber,
+		Sum:    payload.Sum,
+		TTL:    time.Duration(payload.TTL) * time.Second,
+	})
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	// Encode and return the hold. Status 201.
+	if err = json.NewEncoder(w).Encode(response.NewHold(hold, format)); err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+}
+
+// Withdraw held funds (POST /api/user/balance/holds/{id}/capture HTTP/1.1).
+func (c *AccountController) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	c.finishHold(w, r, c.service.CaptureHold)
+}
+
+// Return held funds to the balance (POST /api/user/balance/holds/{id}/release HTTP/1.1).
+func (c *AccountController) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	c.finishHold(w, r, c.service.ReleaseHold)
+}
+
+func (c *AccountController) finishHold(
+	w http.ResponseWriter, r *http.Request,
+	finish func(ctx context.Context, id user.ID, holdID int) (*entities.Hold, error),
+) {
+	// Check the hold ID.
+	holdID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, fmt.Errorf("%w: invalid hold id", errs.ErrInvalidRequest))
+		return
+	}
+
+	// Get user from context.
+	u, found := user.FromContext(r.Context())
+	if !found {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// Get the requested format of amounts.
+	format, err := parseAmountFormat(r)
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Capture or release the hold.
+	hold, err := finish(r.Context(), u.ID, holdID)
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// Encode and return the hold. Status 200.
+	if err = json.NewEncoder(w).Encode(response.NewHold(hold, format)); err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+}
+
 // ErrorHandlerFunc handles sending of an error in the JSON format,
 // writing appropriate status code and handling the failure to marshal that.
 func (c *AccountController) ErrorHandlerFunc(w http.ResponseWriter, _ *http.Request, err error) {
@@ -166,6 +388,10 @@ func (c *AccountController) ErrorHandlerFunc(w http.ResponseWriter, _ *http.Requ
 	code := http.StatusInternalServerError
 
 	switch {
+	// Status Not Found (404).
+	case errors.Is(err, errs.ErrHoldNotFound):
+		code = http.StatusNotFound
+
 	// Status No Content (204).
 	case errors.Is(err, errs.ErrNotFound):
 		code = http.StatusNoContent
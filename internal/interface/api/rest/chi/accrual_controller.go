@@ -0,0 +1,119 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/application/interfaces"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/interface/api/rest/header"
+	"github.com/KretovDmitry/gophermart/internal/interface/api/rest/response/accrual"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+type AccrualController struct {
+	service interfaces.AccrualService
+	logger  logger.Logger
+}
+
+// NewAccrualController registers http.Handlers with additional options.
+func NewAccrualController(
+	service interfaces.AccrualService, logger logger.Logger, options ChiServerOptions,
+) {
+	r := options.BaseRouter
+
+	if r == nil {
+		r = chi.NewRouter()
+	}
+
+	c := AccrualController{
+		service: service,
+		logger:  logger,
+	}
+
+	r.Group(func(r chi.Router) {
+		for _, middleware := range options.Middlewares {
+			r.Use(middleware)
+		}
+		r.Post(options.BaseURL+"/callback", c.Callback)
+	})
+}
+
+// Order update pushed by the accrual system
+// (POST /api/internal/accrual/callback HTTP/1.1).
+func (c *AccrualController) Callback(w http.ResponseWriter, r *http.Request) {
+	// Check content type.
+	if !header.IsApplicationJSONContentType(r) {
+		c.ErrorHandlerFunc(w, r, fmt.Errorf("%w: invalid content type", errs.ErrInvalidRequest))
+		return
+	}
+
+	// Read, decode and close request body.
+	defer r.Body.Close()
+
+	var payload accrual.UpdateOrderInfo
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		c.ErrorHandlerFunc(w, r, checkJSONDecodeError(err))
+		return
+	}
+
+	// Check the order number.
+	if _, err := entities.NewOrderNumber(payload.Order); err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Check the order status.
+	info, err := entities.NewUpdateInfoFromResponse(&payload)
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Update the order and credit the accrual.
+	if err = c.service.ApplyUpdate(r.Context(), info); err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Return 200 OK if there is no error.
+	w.WriteHeader(http.StatusOK)
+}
+
+// ErrorHandlerFunc handles sending of an error in the JSON format,
+// writing appropriate status code and handling the failure to marshal that.
+func (c *AccrualController) ErrorHandlerFunc(w http.ResponseWriter, _ *http.Request, err error) {
+	errJSON := errs.JSON{Error: err.Error()}
+	code := http.StatusInternalServerError
+
+	switch {
+	// Status Bad Request (400).
+	case errors.Is(err, errs.ErrInvalidRequest):
+		code = http.StatusBadRequest
+
+	// Status Not Found (404).
+	case errors.Is(err, errs.ErrNotFound):
+		code = http.StatusNotFound
+
+	// Status Conflict (409).
+	case errors.Is(err, errs.ErrInvalidTransition):
+		code = http.StatusConflict
+
+	// Status Unproccessable Entity (422).
+	case errors.Is(err, errs.ErrInvalidOrderNumber):
+		code = http.StatusUnprocessableEntity
+	}
+
+	w.WriteHeader(code)
+
+	c.logger.Errorf("accrual controller [%d]: %s", code, err)
+
+	if err = json.NewEncoder(w).Encode(errJSON); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
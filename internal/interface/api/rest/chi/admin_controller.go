@@ -0,0 +1,155 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/application/interfaces"
+	"github.com/KretovDmitry/gophermart/internal/interface/api/rest/response"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+type AdminController struct {
+	accrualService interfaces.AccrualService
+	logger         logger.Logger
+}
+
+// NewAdminController registers http.Handlers with additional options.
+func NewAdminController(
+	accrualService interfaces.AccrualService, logger logger.Logger, options ChiServerOptions,
+) {
+	r := options.BaseRouter
+
+	if r == nil {
+		r = chi.NewRouter()
+	}
+
+	c := AdminController{
+		accrualService: accrualService,
+		logger:         logger,
+	}
+
+	r.Group(func(r chi.Router) {
+		for _, middleware := range options.Middlewares {
+			r.Use(middleware)
+		}
+		r.Get(options.BaseURL+"/health", c.GetHealth)
+		r.Get(options.BaseURL+"/metrics", c.GetMetrics)
+		r.Get(options.BaseURL+"/accrual/dead-letters", c.GetDeadLetters)
+		r.Post(options.BaseURL+"/accrual/dead-letters/{id}/replay", c.ReplayDeadLetter)
+		r.Delete(options.BaseURL+"/accrual/dead-letters/{id}", c.DiscardDeadLetter)
+	})
+}
+
+// Get service health (GET /api/admin/health HTTP/1.1).
+func (c *AdminController) GetHealth(w http.ResponseWriter, r *http.Request) {
+	// Create response payload.
+	response := response.NewGetHealth(c.accrualService.CircuitState())
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// Encode and return. Status 200.
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+}
+
+// Get metrics published by expvar (GET /api/admin/metrics HTTP/1.1).
+func (c *AdminController) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	expvar.Handler().ServeHTTP(w, r)
+}
+
+// Get malformed responses of the accrual system
+// (GET /api/admin/accrual/dead-letters HTTP/1.1).
+func (c *AdminController) GetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	letters, err := c.accrualService.GetDeadLetters(r.Context())
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Convert entities to handler response representation.
+	res := make([]*response.GetDeadLetters, len(letters))
+	for i, letter := range letters {
+		res[i] = response.NewGetDeadLetters(letter)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// Encode and return them. Status 200.
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+}
+
+// Check the order of the dead letter again
+// (POST /api/admin/accrual/dead-letters/{id}/replay HTTP/1.1).
+func (c *AdminController) ReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, fmt.Errorf("%w: invalid id", errs.ErrInvalidRequest))
+		return
+	}
+
+	if err = c.accrualService.ReplayDeadLetter(r.Context(), id); err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Return 200 OK if there is no error.
+	w.WriteHeader(http.StatusOK)
+}
+
+// Drop the dead letter (DELETE /api/admin/accrual/dead-letters/{id} HTTP/1.1).
+func (c *AdminController) DiscardDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, fmt.Errorf("%w: invalid id", errs.ErrInvalidRequest))
+		return
+	}
+
+	if err = c.accrualService.DiscardDeadLetter(r.Context(), id); err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Return 200 OK if there is no error.
+	w.WriteHeader(http.StatusOK)
+}
+
+// ErrorHandlerFunc handles sending of an error in the JSON format,
+// writing appropriate status code and handling the failure to marshal that.
+func (c *AdminController) ErrorHandlerFunc(w http.ResponseWriter, _ *http.Request, err error) {
+	errJSON := errs.JSON{Error: err.Error()}
+	code := http.StatusInternalServerError
+
+	switch {
+	// Status Bad Request (400).
+	case errors.Is(err, errs.ErrInvalidRequest):
+		code = http.StatusBadRequest
+
+	// Status Not Found (404).
+	case errors.Is(err, errs.ErrNotFound):
+		code = http.StatusNotFound
+	}
+
+	w.WriteHeader(code)
+
+	c.logger.Errorf("admin controller [%d]: %s", code, err)
+
+	if err = json.NewEncoder(w).Encode(errJSON); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 
 	"github.com/KretovDmitry/gophermart/internal/application/errs"
 )
@@ -19,5 +20,15 @@ func checkJSONDecodeError(err error) error {
 		return fmt.Errorf("%w: empty body", errs.ErrInvalidRequest)
 	}
 
+	return checkReadBodyError(err)
+}
+
+func checkReadBodyError(err error) error {
+	var e *http.MaxBytesError
+	if errors.As(err, &e) {
+		return fmt.Errorf("%w: body is larger than %d bytes",
+			errs.ErrRequestTooLarge, e.Limit)
+	}
+
 	return err
 }
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/KretovDmitry/gophermart/internal/application/errs"
 	"github.com/KretovDmitry/gophermart/internal/application/interfaces"
+	"github.com/KretovDmitry/gophermart/internal/application/params"
 	"github.com/KretovDmitry/gophermart/internal/domain/entities"
 	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
 	"github.com/KretovDmitry/gophermart/internal/interface/api/rest/header"
@@ -42,7 +44,11 @@ func NewOrderController(
 			r.Use(middleware)
 		}
 		r.Post(options.BaseURL+"/orders", c.CreateOrder)
+		r.Post(options.BaseURL+"/orders/batch", c.CreateOrders)
 		r.Get(options.BaseURL+"/orders", c.GetOrders)
+		r.Get(options.BaseURL+"/orders/{number}", c.GetOrder)
+		r.Get(options.BaseURL+"/orders/{number}/history", c.GetOrderHistory)
+		r.Delete(options.BaseURL+"/orders/{number}", c.CancelOrder)
 	})
 }
 
@@ -91,7 +97,86 @@ func (c *OrderController) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusAccepted)
 }
 
+const (
+	// Maximum number of orders in the batch.
+	maxBatchSize = 1000
+	// Bytes of the batch body allowed per order number
+	// with quotes, separators and whitespace.
+	batchBytesPerOrder = 64
+)
+
+// Create new orders from the batch (POST /api/user/orders/batch HTTP/1.1).
+// Body is either JSON array of numbers or numbers on separate lines of plain text.
+func (c *OrderController) CreateOrders(w http.ResponseWriter, r *http.Request) {
+	// Read, decode and close request body.
+	defer r.Body.Close()
+
+	// Limit the body to the size of the largest batch.
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchSize*batchBytesPerOrder)
+
+	var nums []string
+
+	switch {
+	case header.IsApplicationJSONContentType(r):
+		if err := json.NewDecoder(r.Body).Decode(&nums); err != nil {
+			c.ErrorHandlerFunc(w, r, checkJSONDecodeError(err))
+			return
+		}
+	case header.IsTextPlainContentType(r):
+		bytes, err := io.ReadAll(r.Body)
+		if err != nil {
+			c.ErrorHandlerFunc(w, r, checkReadBodyError(err))
+			return
+		}
+		for _, line := range strings.Split(string(bytes), "\n") {
+			if num := strings.TrimSpace(line); num != "" {
+				nums = append(nums, num)
+			}
+		}
+	default:
+		c.ErrorHandlerFunc(w, r, fmt.Errorf("%w: invalid content type", errs.ErrInvalidRequest))
+		return
+	}
+
+	// Check batch size.
+	if len(nums) == 0 || len(nums) > maxBatchSize {
+		c.ErrorHandlerFunc(w, r, fmt.Errorf("%w: batch must have from 1 to %d orders",
+			errs.ErrInvalidRequest, maxBatchSize))
+		return
+	}
+
+	// Get user from context.
+	user, found := user.FromContext(r.Context())
+	if !found {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// Create orders.
+	uploads, err := c.service.CreateOrders(r.Context(), user.ID, nums)
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Convert entities to handler response representation.
+	res := make([]*response.CreateOrders, len(uploads))
+	for i, upload := range uploads {
+		res[i] = response.NewCreateOrders(upload)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// Encode and return them. Status 200.
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+}
+
 // Get user orders (GET /api/user/orders HTTP/1.1).
+// Query: limit, after, status (repeated or comma separated),
+// from and to (RFC3339 upload time), sort (asc or desc).
 func (c *OrderController) GetOrders(w http.ResponseWriter, r *http.Request) {
 	// Get user from context.
 	user, found := user.FromContext(r.Context())
@@ -100,17 +185,179 @@ func (c *OrderController) GetOrders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get all orders for the user.
-	orders, err := c.service.GetOrders(r.Context(), user.ID)
+	// Parse filters and pagination. All the orders are returned by default.
+	params, err := parseListOrders(r, user.ID)
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Get the requested format of amounts.
+	format, err := parseAmountFormat(r)
 	if err != nil {
 		c.ErrorHandlerFunc(w, r, err)
 		return
 	}
 
+	// Get the page of orders for the user.
+	orders, next, err := c.service.GetOrders(r.Context(), params)
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	setNextLink(w, r, next)
+
 	// Convert entities to handler response representation.
 	res := make([]*response.GetOrders, len(orders))
 	for i, order := range orders {
-		res[i] = response.NewGetOrdersFromOrderEntity(order)
+		res[i] = response.NewGetOrdersFromOrderEntity(order, format)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// Encode and return them. Status 200.
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+}
+
+func parseListOrders(r *http.Request, id user.ID) (*params.ListOrders, error) {
+	q := r.URL.Query()
+
+	p := &params.ListOrders{UserID: id}
+
+	var err error
+	if p.Limit, err = parseLimit(q); err != nil {
+		return nil, err
+	}
+	if p.After, err = parseCursor(q); err != nil {
+		return nil, err
+	}
+	if p.From, err = parseTime(q, "from"); err != nil {
+		return nil, err
+	}
+	if p.To, err = parseTime(q, "to"); err != nil {
+		return nil, err
+	}
+	if p.Sort, err = parseSort(q); err != nil {
+		return nil, err
+	}
+
+	for _, v := range q["status"] {
+		for _, status := range strings.Split(v, ",") {
+			switch status := entities.OrderStatus(strings.ToUpper(status)); status {
+			case entities.NEW, entities.PROCESSING, entities.INVALID, entities.PROCESSED:
+				p.Statuses = append(p.Statuses, status)
+			default:
+				return nil, fmt.Errorf("%w: unknown status %q", errs.ErrInvalidRequest, status)
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// Get user order (GET /api/user/orders/{number} HTTP/1.1).
+func (c *OrderController) GetOrder(w http.ResponseWriter, r *http.Request) {
+	// Check the order number.
+	orderNumber, err := entities.NewOrderNumber(chi.URLParam(r, "number"))
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Get user from context.
+	user, found := user.FromContext(r.Context())
+	if !found {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// Get the requested format of amounts.
+	format, err := parseAmountFormat(r)
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Get the order.
+	order, err := c.service.GetOrder(r.Context(), user.ID, orderNumber)
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// Encode and return it. Status 200.
+	if err = json.NewEncoder(w).Encode(response.NewGetOrdersFromOrderEntity(order, format)); err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+}
+
+// Cancel user's order not processed yet (DELETE /api/user/orders/{number} HTTP/1.1).
+func (c *OrderController) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	// Check the order number.
+	orderNumber, err := entities.NewOrderNumber(chi.URLParam(r, "number"))
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Get user from context.
+	user, found := user.FromContext(r.Context())
+	if !found {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// Cancel the order.
+	if err = c.service.CancelOrder(r.Context(), user.ID, orderNumber); err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Status 204.
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Get timeline of user's order (GET /api/user/orders/{number}/history HTTP/1.1).
+func (c *OrderController) GetOrderHistory(w http.ResponseWriter, r *http.Request) {
+	// Check the order number.
+	orderNumber, err := entities.NewOrderNumber(chi.URLParam(r, "number"))
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Get user from context.
+	user, found := user.FromContext(r.Context())
+	if !found {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// Get the requested format of amounts.
+	format, err := parseAmountFormat(r)
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Get the order timeline.
+	history, err := c.service.GetOrderHistory(r.Context(), user.ID, orderNumber)
+	if err != nil {
+		c.ErrorHandlerFunc(w, r, err)
+		return
+	}
+
+	// Convert entities to handler response representation.
+	res := make([]*response.GetOrderHistory, len(history))
+	for i, change := range history {
+		res[i] = response.NewGetOrderHistory(change, format)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -137,6 +384,10 @@ func (c *OrderController) ErrorHandlerFunc(w http.ResponseWriter, _ *http.Reques
 	case errors.Is(err, errs.ErrAlreadyExists):
 		code = http.StatusOK
 
+	// Status Not Found (404).
+	case errors.Is(err, errs.ErrOrderNotFound):
+		code = http.StatusNotFound
+
 	// Status No Content (204).
 	case errors.Is(err, errs.ErrNotFound):
 		code = http.StatusNoContent
@@ -156,6 +407,10 @@ func (c *OrderController) ErrorHandlerFunc(w http.ResponseWriter, _ *http.Reques
 	// Status Unproccessable Entity (422).
 	case errors.Is(err, errs.ErrInvalidOrderNumber):
 		code = http.StatusUnprocessableEntity
+
+	// Status Request Entity Too Large (413).
+	case errors.Is(err, errs.ErrRequestTooLarge):
+		code = http.StatusRequestEntityTooLarge
 	}
 
 	w.WriteHeader(code)
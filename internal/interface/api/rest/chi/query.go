@@ -0,0 +1,110 @@
+package rest
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/application/params"
+	"github.com/KretovDmitry/gophermart/internal/interface/api/rest/response"
+)
+
+// Maximum page size.
+const maxLimit = 1000
+
+// parseLimit returns the page size, zero if not given.
+func parseLimit(q url.Values) (int, error) {
+	v := q.Get("limit")
+	if v == "" {
+		return 0, nil
+	}
+
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit < 1 || limit > maxLimit {
+		return 0, fmt.Errorf("%w: limit must be from 1 to %d", errs.ErrInvalidRequest, maxLimit)
+	}
+
+	return limit, nil
+}
+
+// parseCursor returns the position to start the page after, nil if not given.
+func parseCursor(q url.Values) (*params.Cursor, error) {
+	v := q.Get("after")
+	if v == "" {
+		return nil, nil
+	}
+
+	return params.ParseCursor(v)
+}
+
+// parseTime returns the RFC3339 time, zero if not given.
+func parseTime(q url.Values, key string) (time.Time, error) {
+	v := q.Get(key)
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s must be RFC3339 time", errs.ErrInvalidRequest, key)
+	}
+
+	return t.UTC(), nil
+}
+
+// parseSort returns the sort order, newest first by default.
+func parseSort(q url.Values) (params.Sort, error) {
+	switch sort := params.Sort(q.Get("sort")); sort {
+	case "":
+		return params.SortDesc, nil
+	case params.SortAsc, params.SortDesc:
+		return sort, nil
+	default:
+		return "", fmt.Errorf("%w: sort must be %s or %s", errs.ErrInvalidRequest, params.SortAsc, params.SortDesc)
+	}
+}
+
+// setNextLink sets the Link header to the next page keeping the rest of the query.
+func setNextLink(w http.ResponseWriter, r *http.Request, next *params.Cursor) {
+	if next == nil {
+		return
+	}
+
+	q := r.URL.Query()
+	q.Set("after", next.String())
+
+	link := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+
+	w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", link.String()))
+}
+
+// parseAmountFormat returns the format of amounts in the response selected by
+// the amounts query parameter or the amounts parameter of the Accept header,
+// e.g. "application/json; amounts=string". Amounts are numbers by default.
+func parseAmountFormat(r *http.Request) (response.AmountFormat, error) {
+	v := r.URL.Query().Get("amounts")
+
+	if v == "" {
+		for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+			_, params, err := mime.ParseMediaType(accept)
+			if err == nil && params["amounts"] != "" {
+				v = params["amounts"]
+				break
+			}
+		}
+	}
+
+	switch v {
+	case "", "number":
+		return response.AmountNumber, nil
+	case "string":
+		return response.AmountString, nil
+	default:
+		return 0, fmt.Errorf("%w: amounts must be number or string", errs.ErrInvalidRequest)
+	}
+}
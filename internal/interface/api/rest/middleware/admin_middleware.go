@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+)
+
+// AdminToken middleware lets through requests with
+// the admin token: "Authorization: Bearer <token>".
+func AdminToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			got, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found {
+				errorHandlerFunc(w, r, fmt.Errorf("%w: admin token required", errs.ErrInvalidCredentials))
+				return
+			}
+
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				errorHandlerFunc(w, r, fmt.Errorf("%w: invalid admin token", errs.ErrInvalidCredentials))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(f)
+	}
+}
@@ -0,0 +1,42 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KretovDmitry/gophermart/internal/interface/api/rest/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminToken(t *testing.T) {
+	handler := middleware.AdminToken("token")(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"valid", "Bearer token", http.StatusOK},
+		{"missing", "", http.StatusUnauthorized},
+		{"not bearer", "Basic token", http.StatusUnauthorized},
+		{"invalid", "Bearer forged", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			result := w.Result()
+			defer result.Body.Close()
+
+			assert.Equal(t, tt.want, result.StatusCode)
+		})
+	}
+}
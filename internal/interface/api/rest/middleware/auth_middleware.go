@@ -46,8 +46,17 @@ func errorHandlerFunc(w http.ResponseWriter, _ *http.Request, err error) {
 	errJSON := errs.JSON{Error: err.Error()}
 	code := http.StatusInternalServerError
 
-	if errors.Is(err, errs.ErrNotFound) || errors.Is(err, errs.ErrInvalidCredentials) {
+	switch {
+	case errors.Is(err, errs.ErrNotFound) || errors.Is(err, errs.ErrInvalidCredentials):
 		code = http.StatusUnauthorized
+	case errors.Is(err, errs.ErrInvalidRequest):
+		code = http.StatusBadRequest
+	case errors.Is(err, errs.ErrDataConflict):
+		code = http.StatusConflict
+	case errors.Is(err, errs.ErrIdempotencyKeyUsed):
+		code = http.StatusUnprocessableEntity
+	case errors.Is(err, errs.ErrRequestTooLarge):
+		code = http.StatusRequestEntityTooLarge
 	}
 
 	w.WriteHeader(code)
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/application/interfaces"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	// IdempotencyKeyHeader carries the key the client retries the request with.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks the response replayed to the retry.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+const (
+	// Maximum length of the idempotency key.
+	maxIdempotencyKeyLength = 255
+	// Maximum size of the request body compared between retries.
+	maxIdempotentBodySize = 1 << 20
+)
+
+// Idempotency middleware replays the first response to the user's POST request
+// retried with the same Idempotency-Key header. The key reused with another
+// request is rejected, so is the body over 1MB. Requests without the key
+// are passed through.
+// It must follow the authorization middleware.
+func Idempotency(service interfaces.IdempotencyService, logger logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+			if r.Method != http.MethodPost || idempotencyKey == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(idempotencyKey) > maxIdempotencyKeyLength {
+				errorHandlerFunc(w, r, fmt.Errorf("%w: idempotency key is longer than %d",
+					errs.ErrInvalidRequest, maxIdempotencyKeyLength))
+				return
+			}
+
+			u, found := user.FromContext(r.Context())
+			if !found {
+				errorHandlerFunc(w, r, fmt.Errorf("user: %w", errs.ErrNotFound))
+				return
+			}
+
+			// One byte over the limit tells the body is too large to be compared.
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBodySize+1))
+			if err != nil {
+				errorHandlerFunc(w, r, fmt.Errorf("read body: %w", err))
+				return
+			}
+			r.Body.Close()
+
+			if len(body) > maxIdempotentBodySize {
+				errorHandlerFunc(w, r, fmt.Errorf("%w: more than %d bytes",
+					errs.ErrRequestTooLarge, maxIdempotentBodySize))
+				return
+			}
+
+			key := &entities.IdempotencyKey{
+				UserID:      u.ID,
+				Key:         idempotencyKey,
+				RequestHash: requestHash(r, body),
+			}
+
+			saved, err := service.Begin(r.Context(), key)
+			if err != nil {
+				errorHandlerFunc(w, r, err)
+				return
+			}
+
+			// Replay the response to the retry.
+			if saved != nil {
+				if saved.ContentType != "" {
+					w.Header().Set("Content-Type", saved.ContentType)
+				}
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(saved.StatusCode)
+				_, _ = w.Write(saved.Body)
+				return
+			}
+
+			// Give the read body to the next handler and record its response.
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			var buf bytes.Buffer
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&buf)
+
+			next.ServeHTTP(ww, r)
+
+			res := &entities.IdempotentResponse{
+				StatusCode:  ww.Status(),
+				ContentType: ww.Header().Get("Content-Type"),
+				Body:        buf.Bytes(),
+			}
+			if res.StatusCode == 0 {
+				res.StatusCode = http.StatusOK
+			}
+
+			// The response is sent already, so it is saved even if the client is gone.
+			if err = service.Finish(context.WithoutCancel(r.Context()), key, res); err != nil {
+				logger.Errorf("idempotency key %q: %v", idempotencyKey, err)
+			}
+		}
+
+		return http.HandlerFunc(f)
+	}
+}
+
+// requestHash identifies the request by its method, path and body.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.Path)
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,97 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/internal/domain/entities/user"
+	"github.com/KretovDmitry/gophermart/internal/interface/api/rest/middleware"
+	"github.com/KretovDmitry/gophermart/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotency(t *testing.T) {
+	logger, _ := logger.NewForTest()
+
+	calls := 0
+	handler := middleware.Idempotency(&stubIdempotencyService{keys: make(map[string]*entities.IdempotencyKey)}, logger)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusPaymentRequired)
+			_, _ = w.Write([]byte(`{"error":"not enough funds"}`))
+		}))
+
+	tests := []struct {
+		name     string
+		key      string
+		body     string
+		want     int
+		replayed bool
+		calls    int
+	}{
+		{"first", "key", `{"order":"2377225624","sum":751}`, http.StatusPaymentRequired, false, 1},
+		{"retry", "key", `{"order":"2377225624","sum":751}`, http.StatusPaymentRequired, true, 1},
+		{"other body", "key", `{"order":"2377225624","sum":752}`, http.StatusUnprocessableEntity, false, 1},
+		{"without key", "", `{"order":"2377225624","sum":751}`, http.StatusPaymentRequired, false, 2},
+		{"too long key", strings.Repeat("k", 256), `{}`, http.StatusBadRequest, false, 2},
+		{"too large body", "large", strings.Repeat(" ", 1<<20+1), http.StatusRequestEntityTooLarge, false, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", strings.NewReader(tt.body))
+			r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: 1}))
+			if tt.key != "" {
+				r.Header.Set(middleware.IdempotencyKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			result := w.Result()
+			defer result.Body.Close()
+
+			require.Equal(t, tt.want, result.StatusCode)
+			assert.Equal(t, tt.replayed, result.Header.Get(middleware.IdempotentReplayedHeader) == "true")
+			assert.Equal(t, tt.calls, calls)
+			if tt.replayed {
+				assert.Equal(t, "application/json", result.Header.Get("Content-Type"))
+				assert.JSONEq(t, `{"error":"not enough funds"}`, w.Body.String())
+			}
+		})
+	}
+}
+
+// stubIdempotencyService keeps keys of the single user in memory.
+type stubIdempotencyService struct {
+	keys map[string]*entities.IdempotencyKey
+}
+
+func (s *stubIdempotencyService) Begin(
+	_ context.Context, key *entities.IdempotencyKey,
+) (*entities.IdempotentResponse, error) {
+	saved, ok := s.keys[key.Key]
+	if !ok {
+		s.keys[key.Key] = key
+		return nil, nil
+	}
+	if saved.RequestHash != key.RequestHash {
+		return nil, errs.ErrIdempotencyKeyUsed
+	}
+
+	return saved.Response, nil
+}
+
+func (s *stubIdempotencyService) Finish(
+	_ context.Context, key *entities.IdempotencyKey, res *entities.IdempotentResponse,
+) error {
+	s.keys[key.Key].Response = res
+	return nil
+}
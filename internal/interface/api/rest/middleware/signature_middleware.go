@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
+)
+
+const (
+	// SignatureHeader carries HMAC-SHA256 of the timestamp and the request body
+	// joined with a dot: "sha256=<hex>".
+	SignatureHeader = "X-Accrual-Signature"
+	// SignatureTimestampHeader carries the signing time in Unix seconds.
+	SignatureTimestampHeader = "X-Accrual-Timestamp"
+)
+
+const (
+	// Maximum size of the signed request body.
+	maxSignedBodySize = 1 << 20
+	// Maximum difference between the signing time and the server time.
+	// Older requests are rejected so that they can't be replayed later.
+	maxSignatureAge = 5 * time.Minute
+)
+
+// Signature middleware verifies that the request body and the timestamp
+// are signed with the secret and the timestamp is recent.
+func Signature(secret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			signature, found := strings.CutPrefix(r.Header.Get(SignatureHeader), "sha256=")
+			if !found {
+				errorHandlerFunc(w, r, fmt.Errorf("%w: signature required", errs.ErrInvalidCredentials))
+				return
+			}
+
+			expected, err := hex.DecodeString(signature)
+			if err != nil {
+				errorHandlerFunc(w, r, fmt.Errorf("%w: malformed signature", errs.ErrInvalidCredentials))
+				return
+			}
+
+			timestamp := r.Header.Get(SignatureTimestampHeader)
+			unix, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil {
+				errorHandlerFunc(w, r, fmt.Errorf("%w: signature timestamp required", errs.ErrInvalidCredentials))
+				return
+			}
+
+			if age := time.Since(time.Unix(unix, 0)); age > maxSignatureAge || age < -maxSignatureAge {
+				errorHandlerFunc(w, r, fmt.Errorf("%w: signature timestamp is off by %s",
+					errs.ErrInvalidCredentials, age.Round(time.Second)))
+				return
+			}
+
+			// One byte over the limit tells the body is too large to be verified.
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxSignedBodySize+1))
+			if err != nil {
+				errorHandlerFunc(w, r, fmt.Errorf("read body: %w", err))
+				return
+			}
+			r.Body.Close()
+
+			if len(body) > maxSignedBodySize {
+				errorHandlerFunc(w, r, fmt.Errorf("%w: more than %d bytes",
+					errs.ErrRequestTooLarge, maxSignedBodySize))
+				return
+			}
+
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write([]byte(timestamp + "."))
+			mac.Write(body)
+
+			if !hmac.Equal(mac.Sum(nil), expected) {
+				errorHandlerFunc(w, r, fmt.Errorf("%w: signature mismatch", errs.ErrInvalidCredentials))
+				return
+			}
+
+			// Give the verified body to the next handler.
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(f)
+	}
+}
@@ -0,0 +1,78 @@
+package middleware_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/interface/api/rest/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignature(t *testing.T) {
+	const (
+		secret = "secret"
+		body   = `{"order":"79927398713","status":"PROCESSED","accrual":500}`
+	)
+
+	sign := func(timestamp, body string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(timestamp + "." + body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+	large := strings.Repeat(" ", 1<<20+1)
+
+	handler := middleware.Signature(secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, body, string(got))
+	}))
+
+	tests := []struct {
+		name      string
+		body      string
+		timestamp string
+		signature string
+		want      int
+	}{
+		{"valid", body, now, sign(now, body), http.StatusOK},
+		{"missing", body, now, "", http.StatusUnauthorized},
+		{"malformed", body, now, "sha256=zz", http.StatusUnauthorized},
+		{"mismatch", body, now, "sha256=" + hex.EncodeToString([]byte("forged")), http.StatusUnauthorized},
+		{"without timestamp", body, "", sign("", body), http.StatusUnauthorized},
+		{"stale", body, stale, sign(stale, body), http.StatusUnauthorized},
+		{"timestamp not signed", body, now, sign(stale, body), http.StatusUnauthorized},
+		{"too large", large, now, sign(now, large), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.signature != "" {
+				r.Header.Set(middleware.SignatureHeader, tt.signature)
+			}
+			if tt.timestamp != "" {
+				r.Header.Set(middleware.SignatureTimestampHeader, tt.timestamp)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			result := w.Result()
+			defer result.Body.Close()
+
+			assert.Equal(t, tt.want, result.StatusCode)
+		})
+	}
+}
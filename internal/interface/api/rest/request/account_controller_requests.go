@@ -6,3 +6,10 @@ type Withdraw struct {
 	Order string          `json:"order"`
 	Sum   decimal.Decimal `json:"sum"`
 }
+
+type CreateHold struct {
+	Order string          `json:"order"`
+	Sum   decimal.Decimal `json:"sum"`
+	// Seconds to hold the funds for. Zero holds them for the default time.
+	TTL int `json:"ttl"`
+}
@@ -7,27 +7,69 @@ import (
 )
 
 type GetBalance struct {
-	Balance   float64 `json:"current"`
-	Withdrawn float64 `json:"withdrawn"`
+	Balance   Amount `json:"current"`
+	Withdrawn Amount `json:"withdrawn"`
+	Held      Amount `json:"held"`
 }
 
-func NewGetBalance(e *entities.Account) GetBalance {
+func NewGetBalance(e *entities.Account, f AmountFormat) GetBalance {
 	return GetBalance{
-		Balance:   e.Balance.InexactFloat64(),
-		Withdrawn: e.Withdrawn.InexactFloat64(),
+		Balance:   NewAmount(e.Balance, f),
+		Withdrawn: NewAmount(e.Withdrawn, f),
+		Held:      NewAmount(e.Held, f),
 	}
 }
 
 type GetWithdrawals struct {
 	ProcessedAt time.Time            `json:"processed_at"`
 	Order       entities.OrderNumber `json:"order"`
-	Sum         float64              `json:"sum"`
+	Sum         Amount               `json:"sum"`
 }
 
-func NewGetWithdrawals(e *entities.Withdrawal) *GetWithdrawals {
+func NewGetWithdrawals(e *entities.Withdrawal, f AmountFormat) *GetWithdrawals {
 	return &GetWithdrawals{
 		Order:       e.Order,
-		Sum:         e.Sum.InexactFloat64(),
+		Sum:         NewAmount(e.Sum, f),
 		ProcessedAt: e.ProcessedAt,
 	}
 }
+
+type GetStatement struct {
+	ProcessedAt time.Time              `json:"processed_at"`
+	Type        entities.OperationType `json:"type"`
+	Order       entities.OrderNumber   `json:"order,omitempty"`
+	Sum         Amount                 `json:"sum"`
+	Balance     Amount                 `json:"balance"`
+	Backfilled  bool                   `json:"backfilled,omitempty"`
+}
+
+func NewGetStatement(e *entities.StatementEntry, f AmountFormat) *GetStatement {
+	return &GetStatement{
+		ProcessedAt: e.ProcessedAt,
+		Type:        e.Type,
+		Order:       e.Order,
+		Sum:         NewAmount(e.Sum, f),
+		Balance:     NewAmount(e.Balance, f),
+		Backfilled:  e.Backfilled,
+	}
+}
+
+type Hold struct {
+	ID        int                  `json:"id"`
+	Order     entities.OrderNumber `json:"order"`
+	Sum       Amount               `json:"sum"`
+	Status    entities.HoldStatus  `json:"status"`
+	CreatedAt time.Time            `json:"created_at"`
+	ExpiresAt time.Time            `json:"expires_at"`
+}
+
+func NewHold(e *entities.Hold, f AmountFormat) *Hold {
+	return &Hold{
+		ID:        e.ID,
+		Order:     e.Order,
+		Sum:       NewAmount(e.Sum, f),
+		Status:    e.Status,
+		CreatedAt: e.CreatedAt,
+		ExpiresAt: e.ExpiresAt,
+	}
+}
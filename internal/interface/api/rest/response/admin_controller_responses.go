@@ -0,0 +1,51 @@
+package response
+
+import (
+	"time"
+
+	"github.com/KretovDmitry/gophermart/internal/domain/entities"
+	"github.com/KretovDmitry/gophermart/pkg/breaker"
+)
+
+type GetHealth struct {
+	Status  string        `json:"status"`
+	Accrual AccrualHealth `json:"accrual"`
+}
+
+type AccrualHealth struct {
+	Circuit string `json:"circuit"`
+}
+
+// NewGetHealth reports the service as degraded
+// while the accrual service is not reliably available.
+func NewGetHealth(circuit breaker.State) GetHealth {
+	status := "ok"
+	if circuit != breaker.Closed {
+		status = "degraded"
+	}
+
+	return GetHealth{
+		Status:  status,
+		Accrual: AccrualHealth{Circuit: circuit.String()},
+	}
+}
+
+type GetDeadLetters struct {
+	ID         int                  `json:"id"`
+	Order      entities.OrderNumber `json:"order"`
+	StatusCode int                  `json:"status_code"`
+	Body       string               `json:"body"`
+	Error      string               `json:"error"`
+	CreatedAt  time.Time            `json:"created_at"`
+}
+
+func NewGetDeadLetters(e *entities.DeadLetter) *GetDeadLetters {
+	return &GetDeadLetters{
+		ID:         e.ID,
+		Order:      e.Order,
+		StatusCode: e.StatusCode,
+		Body:       e.Body,
+		Error:      e.Error,
+		CreatedAt:  e.CreatedAt,
+	}
+}
@@ -0,0 +1,52 @@
+package response
+
+import (
+	"encoding/json"
+
+	"github.com/shopspring/decimal"
+)
+
+// AmountFormat selects how amounts of loyalty points are serialized.
+type AmountFormat int
+
+const (
+	// Amounts are JSON numbers, the nearest float64 to the stored value.
+	// The default for backwards compatibility.
+	AmountNumber AmountFormat = iota
+	// Amounts are exact decimal strings.
+	AmountString
+)
+
+// Amount is the sum of loyalty points serialized in the requested format.
+type Amount struct {
+	value  decimal.Decimal
+	format AmountFormat
+}
+
+func NewAmount(value decimal.Decimal, format AmountFormat) Amount {
+	return Amount{value: value, format: format}
+}
+
+// newOptionalAmount returns nil for zero value to omit it.
+func newOptionalAmount(value decimal.Decimal, format AmountFormat) *Amount {
+	if value.IsZero() {
+		return nil
+	}
+
+	amount := NewAmount(value, format)
+
+	return &amount
+}
+
+// MarshalJSON applies the serialization rules of all the amounts in responses.
+// String amounts are never rounded: they keep every digit of the stored
+// numeric(20, 10) value with trailing zeros trimmed, so 0.1000000001 is
+// "0.1000000001" and 500.0000000000 is "500". Number amounts can't
+// represent the value exactly.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	if a.format == AmountString {
+		return json.Marshal(a.value.String())
+	}
+
+	return json.Marshal(a.value.InexactFloat64())
+}
@@ -0,0 +1,36 @@
+package response_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/KretovDmitry/gophermart/internal/interface/api/rest/response"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAmountMarshalJSON(t *testing.T) {
+	tests := []struct {
+		value  string
+		format response.AmountFormat
+		want   string
+	}{
+		{"729.98", response.AmountNumber, `729.98`},
+		{"500", response.AmountNumber, `500`},
+		{"729.98", response.AmountString, `"729.98"`},
+		{"500.0000000000", response.AmountString, `"500"`},
+		{"729.985", response.AmountString, `"729.985"`},
+		{"-0.005", response.AmountString, `"-0.005"`},
+		{"0.1000000001", response.AmountString, `"0.1000000001"`},
+		{"9999999999.9999999999", response.AmountString, `"9999999999.9999999999"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			b, err := json.Marshal(response.NewAmount(decimal.RequireFromString(tt.value), tt.format))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(b))
+		})
+	}
+}
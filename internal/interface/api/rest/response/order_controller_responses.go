@@ -1,23 +1,61 @@
 package response
 
 import (
+	"errors"
 	"time"
 
+	"github.com/KretovDmitry/gophermart/internal/application/errs"
 	"github.com/KretovDmitry/gophermart/internal/domain/entities"
 )
 
 type GetOrders struct {
 	Number     entities.OrderNumber `json:"number"`
 	Status     entities.OrderStatus `json:"status"`
-	Accrual    float64              `json:"accrual,omitempty"`
+	Accrual    *Amount              `json:"accrual,omitempty"`
 	UploadetAt time.Time            `json:"uploadet_at"`
 }
 
-func NewGetOrdersFromOrderEntity(e *entities.Order) *GetOrders {
+func NewGetOrdersFromOrderEntity(e *entities.Order, f AmountFormat) *GetOrders {
 	return &GetOrders{
 		Number:     e.Number,
 		Status:     e.Status,
-		Accrual:    e.Accrual.InexactFloat64(),
+		Accrual:    newOptionalAmount(e.Accrual, f),
 		UploadetAt: e.UploadetAt,
 	}
 }
+
+type GetOrderHistory struct {
+	Status    entities.OrderStatus `json:"status"`
+	Accrual   *Amount              `json:"accrual,omitempty"`
+	Reason    string               `json:"reason,omitempty"`
+	ChangedAt time.Time            `json:"changed_at"`
+}
+
+func NewGetOrderHistory(e *entities.OrderStatusChange, f AmountFormat) *GetOrderHistory {
+	return &GetOrderHistory{
+		Status:    e.Status,
+		Accrual:   newOptionalAmount(e.Accrual, f),
+		Reason:    e.Reason,
+		ChangedAt: e.ChangedAt,
+	}
+}
+
+type CreateOrders struct {
+	Number string `json:"number"`
+	// One of accepted, already_uploaded, conflict or invalid.
+	Result string `json:"result"`
+}
+
+func NewCreateOrders(e *entities.OrderUpload) *CreateOrders {
+	result := "accepted"
+	switch {
+	case errors.Is(e.Err, errs.ErrAlreadyExists):
+		result = "already_uploaded"
+	case errors.Is(e.Err, errs.ErrDataConflict):
+		result = "conflict"
+	case errors.Is(e.Err, errs.ErrInvalidOrderNumber):
+		result = "invalid"
+	}
+
+	return &CreateOrders{Number: e.Number, Result: result}
+}
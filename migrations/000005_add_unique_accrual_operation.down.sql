@@ -0,0 +1,4 @@
+DROP INDEX unique_accrual_order_number;
+
+ALTER TABLE account_operations
+    DROP COLUMN backfilled;
@@ -0,0 +1,28 @@
+CREATE UNIQUE INDEX unique_accrual_order_number ON account_operations (order_number)
+WHERE
+    operation = 'ACCRUAL';
+
+-- Operations restored from the orders rather than recorded when they happened.
+ALTER TABLE account_operations
+    ADD COLUMN backfilled boolean NOT NULL DEFAULT FALSE;
+
+-- Orders don't keep the time they were credited, so the upload time
+-- stands for it and the operations are marked as backfilled.
+INSERT INTO account_operations (account_id, operation, order_number, sum, processed_at, backfilled)
+SELECT
+    a.id,
+    'ACCRUAL',
+    o.number,
+    o.accrual,
+    o.uploadet_at,
+    TRUE
+FROM
+    orders o
+    JOIN accounts a ON a.user_id = o.user_id
+WHERE
+    o.status = 'PROCESSED'
+    AND o.accrual > 0
+ON CONFLICT (order_number)
+WHERE
+    operation = 'ACCRUAL'
+    DO NOTHING;
@@ -0,0 +1,4 @@
+ALTER TABLE orders
+    DROP COLUMN claimed_by,
+    DROP COLUMN claimed_until;
+
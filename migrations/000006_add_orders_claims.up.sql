@@ -0,0 +1,4 @@
+ALTER TABLE orders
+    ADD COLUMN claimed_by text,
+    ADD COLUMN claimed_until timestamp;
+
@@ -0,0 +1,2 @@
+DROP INDEX orders_unprocessed_id_idx;
+
@@ -0,0 +1,4 @@
+CREATE INDEX orders_unprocessed_id_idx ON orders (id)
+WHERE
+    status IN ('NEW', 'PROCESSING');
+
@@ -0,0 +1,5 @@
+ALTER TABLE orders
+    DROP COLUMN attempts,
+    DROP COLUMN last_error,
+    DROP COLUMN next_check_at;
+
@@ -0,0 +1,5 @@
+ALTER TABLE orders
+    ADD COLUMN attempts integer NOT NULL DEFAULT 0,
+    ADD COLUMN last_error text,
+    ADD COLUMN next_check_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
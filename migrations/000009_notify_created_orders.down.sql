@@ -0,0 +1,4 @@
+DROP TRIGGER order_created ON orders;
+
+DROP FUNCTION notify_order_created;
+
@@ -0,0 +1,16 @@
+CREATE FUNCTION notify_order_created ()
+    RETURNS TRIGGER
+    AS $$
+BEGIN
+    PERFORM
+        pg_notify('orders_created', NEW.number);
+    RETURN NULL;
+END;
+$$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER order_created
+    AFTER INSERT ON orders
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_order_created ();
+
@@ -0,0 +1,4 @@
+DROP TRIGGER order_status_transition ON orders;
+
+DROP FUNCTION check_order_status_transition;
+
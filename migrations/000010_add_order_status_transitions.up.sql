@@ -0,0 +1,24 @@
+CREATE FUNCTION check_order_status_transition ()
+    RETURNS TRIGGER
+    AS $$
+BEGIN
+    IF OLD.status IN ('PROCESSED', 'INVALID') THEN
+        RAISE EXCEPTION 'order % is final', OLD.number
+            USING ERRCODE = 'check_violation';
+    END IF;
+    IF NEW.status IS DISTINCT FROM OLD.status AND NOT (
+        (OLD.status = 'NEW' AND NEW.status IN ('PROCESSING', 'PROCESSED', 'INVALID'))
+        OR (OLD.status = 'PROCESSING' AND NEW.status IN ('PROCESSED', 'INVALID'))) THEN
+        RAISE EXCEPTION 'order % status transition % -> % is not allowed', OLD.number, OLD.status, NEW.status
+            USING ERRCODE = 'check_violation';
+    END IF;
+    RETURN NEW;
+END;
+$$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER order_status_transition
+    BEFORE UPDATE OF status, accrual ON orders
+    FOR EACH ROW
+    EXECUTE FUNCTION check_order_status_transition ();
+
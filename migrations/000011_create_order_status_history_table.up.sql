@@ -0,0 +1,10 @@
+CREATE TABLE order_status_history (
+    id serial PRIMARY KEY,
+    order_id integer NOT NULL REFERENCES orders ON DELETE CASCADE,
+    status order_status NOT NULL,
+    accrual numeric(20, 10) NOT NULL DEFAULT 0,
+    changed_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX order_status_history_order_id_idx ON order_status_history (order_id, changed_at);
+
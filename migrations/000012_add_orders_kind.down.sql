@@ -0,0 +1,18 @@
+DROP TRIGGER order_created ON orders;
+
+CREATE TRIGGER order_created
+    AFTER INSERT ON orders
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_order_created ();
+
+DROP INDEX orders_unprocessed_id_idx;
+
+CREATE INDEX orders_unprocessed_id_idx ON orders (id)
+WHERE
+    status IN ('NEW', 'PROCESSING');
+
+ALTER TABLE orders
+    DROP COLUMN kind;
+
+DROP TYPE order_kind;
+
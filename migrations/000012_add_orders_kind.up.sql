@@ -0,0 +1,36 @@
+CREATE TYPE order_kind AS ENUM (
+    'ACCRUAL',
+    'WITHDRAWAL'
+);
+
+ALTER TABLE orders
+    ADD COLUMN kind order_kind NOT NULL DEFAULT 'ACCRUAL';
+
+UPDATE
+    orders
+SET
+    kind = 'WITHDRAWAL'
+WHERE
+    number IN (
+        SELECT
+            order_number
+        FROM
+            account_operations
+        WHERE
+            operation = 'WITHDRAWAL');
+
+DROP INDEX orders_unprocessed_id_idx;
+
+CREATE INDEX orders_unprocessed_id_idx ON orders (id)
+WHERE
+    kind = 'ACCRUAL'
+    AND status IN ('NEW', 'PROCESSING');
+
+DROP TRIGGER order_created ON orders;
+
+CREATE TRIGGER order_created
+    AFTER INSERT ON orders
+    FOR EACH ROW
+    WHEN (NEW.kind = 'ACCRUAL')
+    EXECUTE FUNCTION notify_order_created ();
+
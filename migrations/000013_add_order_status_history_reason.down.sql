@@ -0,0 +1,3 @@
+ALTER TABLE order_status_history
+    DROP COLUMN reason;
+
@@ -0,0 +1,2 @@
+DROP TABLE accrual_dead_letters;
+
@@ -0,0 +1,9 @@
+CREATE TABLE accrual_dead_letters (
+    id serial PRIMARY KEY,
+    order_number text NOT NULL REFERENCES orders (number) ON DELETE CASCADE,
+    status_code integer NOT NULL,
+    body text NOT NULL,
+    error text NOT NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
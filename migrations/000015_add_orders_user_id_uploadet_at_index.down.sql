@@ -0,0 +1,2 @@
+DROP INDEX orders_user_id_uploadet_at_idx;
+
@@ -0,0 +1,2 @@
+CREATE INDEX orders_user_id_uploadet_at_idx ON orders (user_id, uploadet_at, id);
+
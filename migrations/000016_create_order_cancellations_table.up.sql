@@ -0,0 +1,13 @@
+-- The audit outlives the order and the user, so their ids
+-- are kept as plain columns without references.
+CREATE TABLE order_cancellations (
+    id serial PRIMARY KEY,
+    order_id integer NOT NULL,
+    user_id integer NOT NULL,
+    number text NOT NULL,
+    uploadet_at timestamp NOT NULL,
+    cancelled_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX order_cancellations_number_idx ON order_cancellations (number);
+
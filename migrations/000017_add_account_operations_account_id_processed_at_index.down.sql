@@ -0,0 +1,2 @@
+DROP INDEX account_operations_account_id_processed_at_idx;
+
@@ -0,0 +1,2 @@
+CREATE INDEX account_operations_account_id_processed_at_idx ON account_operations (account_id, processed_at, id);
+
@@ -0,0 +1,13 @@
+CREATE TABLE idempotency_keys (
+    user_id integer NOT NULL REFERENCES users ON DELETE CASCADE,
+    key varchar(255) NOT NULL,
+    request_hash text NOT NULL,
+    status_code integer,
+    content_type text,
+    body bytea,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX idempotency_keys_created_at_idx ON idempotency_keys (created_at);
+
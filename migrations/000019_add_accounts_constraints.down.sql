@@ -0,0 +1,34 @@
+-- Merged accounts and written off balances are not restored.
+ALTER TABLE accounts
+    DROP CONSTRAINT non_negative_balance;
+
+ALTER TABLE accounts
+    DROP CONSTRAINT unique_account_user_id;
+
+DELETE FROM account_operations
+WHERE operation = 'ADJUSTMENT';
+
+ALTER TABLE account_operations
+    DROP CONSTRAINT order_number_unless_adjustment;
+
+ALTER TABLE account_operations
+    ALTER COLUMN order_number SET NOT NULL;
+
+DROP INDEX unique_accrual_order_number;
+
+ALTER TYPE account_operation RENAME TO account_operation_old;
+
+CREATE TYPE account_operation AS ENUM (
+    'ACCRUAL',
+    'WITHDRAWAL'
+);
+
+ALTER TABLE account_operations
+    ALTER COLUMN operation TYPE account_operation
+    USING operation::text::account_operation;
+
+DROP TYPE account_operation_old;
+
+CREATE UNIQUE INDEX unique_accrual_order_number ON account_operations (order_number)
+WHERE
+    operation = 'ACCRUAL';
@@ -0,0 +1,98 @@
+-- Existing rows are fixed before the constraints are added, so that
+-- the migration doesn't fail on the data the old code allowed.
+
+-- Duplicate accounts of the same user are merged into the oldest one:
+-- balances and withdrawals are summed and operations are moved to it.
+UPDATE
+    accounts a
+SET
+    balance = t.balance,
+    withdrawn = t.withdrawn
+FROM (
+    SELECT
+        min(id) AS id,
+        sum(balance) AS balance,
+        sum(withdrawn) AS withdrawn
+    FROM
+        accounts
+    GROUP BY
+        user_id
+    HAVING
+        count(*) > 1) t
+WHERE
+    a.id = t.id;
+
+UPDATE
+    account_operations o
+SET
+    account_id = k.keep_id
+FROM (
+    SELECT
+        id,
+        min(id) OVER (PARTITION BY user_id) AS keep_id
+    FROM
+        accounts) k
+WHERE
+    o.account_id = k.id
+    AND k.id <> k.keep_id;
+
+DELETE FROM accounts a USING accounts k
+WHERE k.user_id = a.user_id
+    AND k.id < a.id;
+
+-- Adjustments correct the balance outside of any order. The type is
+-- recreated rather than extended, so that the new value can be used
+-- in this migration.
+DROP INDEX unique_accrual_order_number;
+
+ALTER TYPE account_operation RENAME TO account_operation_old;
+
+CREATE TYPE account_operation AS ENUM (
+    'ACCRUAL',
+    'WITHDRAWAL',
+    'ADJUSTMENT'
+);
+
+ALTER TABLE account_operations
+    ALTER COLUMN operation TYPE account_operation
+    USING operation::text::account_operation;
+
+DROP TYPE account_operation_old;
+
+CREATE UNIQUE INDEX unique_accrual_order_number ON account_operations (order_number)
+WHERE
+    operation = 'ACCRUAL';
+
+ALTER TABLE account_operations
+    ALTER COLUMN order_number DROP NOT NULL;
+
+ALTER TABLE account_operations
+    ADD CONSTRAINT order_number_unless_adjustment
+    CHECK ((operation = 'ADJUSTMENT') = (order_number IS NULL));
+
+-- Negative balances left by the old withdrawals are written off:
+-- the overdraft stays recorded in withdrawn and in the operations,
+-- and the adjustment crediting it keeps the statement in line
+-- with the balance.
+INSERT INTO account_operations (account_id, operation, sum)
+SELECT
+    id,
+    'ADJUSTMENT',
+    - balance
+FROM
+    accounts
+WHERE
+    balance < 0;
+
+UPDATE
+    accounts
+SET
+    balance = 0
+WHERE
+    balance < 0;
+
+ALTER TABLE accounts
+    ADD CONSTRAINT unique_account_user_id UNIQUE (user_id);
+
+ALTER TABLE accounts
+    ADD CONSTRAINT non_negative_balance CHECK (balance >= 0);
@@ -0,0 +1,23 @@
+DELETE FROM account_operations
+WHERE operation IN ('HOLD', 'CAPTURE', 'RELEASE');
+
+DROP INDEX unique_accrual_order_number;
+
+ALTER TYPE account_operation RENAME TO account_operation_old;
+
+CREATE TYPE account_operation AS ENUM (
+    'ACCRUAL',
+    'WITHDRAWAL',
+    'ADJUSTMENT'
+);
+
+ALTER TABLE account_operations
+    ALTER COLUMN operation TYPE account_operation
+    USING operation::text::account_operation;
+
+DROP TYPE account_operation_old;
+
+CREATE UNIQUE INDEX unique_accrual_order_number ON account_operations (order_number)
+WHERE
+    operation = 'ACCRUAL';
+
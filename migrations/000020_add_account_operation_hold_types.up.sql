@@ -0,0 +1,6 @@
+ALTER TYPE account_operation ADD VALUE 'HOLD';
+
+ALTER TYPE account_operation ADD VALUE 'CAPTURE';
+
+ALTER TYPE account_operation ADD VALUE 'RELEASE';
+
@@ -0,0 +1,7 @@
+ALTER TABLE accounts
+    DROP COLUMN held;
+
+DROP TABLE holds;
+
+DROP TYPE hold_status;
+
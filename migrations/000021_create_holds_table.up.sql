@@ -0,0 +1,27 @@
+CREATE TYPE hold_status AS ENUM (
+    'ACTIVE',
+    'CAPTURED',
+    'RELEASED'
+);
+
+CREATE TABLE holds (
+    id serial PRIMARY KEY,
+    account_id integer NOT NULL REFERENCES accounts ON DELETE RESTRICT,
+    order_number text NOT NULL REFERENCES orders (number) ON DELETE RESTRICT,
+    sum numeric(20, 10) NOT NULL CHECK (sum > 0),
+    status hold_status NOT NULL DEFAULT 'ACTIVE',
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at timestamp NOT NULL,
+    resolved_at timestamp
+);
+
+CREATE INDEX holds_active_expires_at_idx ON holds (expires_at)
+WHERE
+    status = 'ACTIVE';
+
+ALTER TABLE accounts
+    ADD COLUMN held numeric(20, 10) NOT NULL DEFAULT 0;
+
+ALTER TABLE accounts
+    ADD CONSTRAINT non_negative_held CHECK (held >= 0);
+
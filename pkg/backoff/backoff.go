@@ -0,0 +1,35 @@
+// Package backoff calculates delays between retries.
+package backoff
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Policy of exponential backoff with jitter.
+type Policy struct {
+	// Delay after the first attempt.
+	Base time.Duration
+	// Multiplier applied to the delay after each attempt.
+	Factor float64
+	// Maximum delay.
+	Cap time.Duration
+	// Fraction of the delay in [0, 1] to be randomly subtracted from it.
+	Jitter float64
+}
+
+// Delay returns the delay after the given number of previous attempts.
+func (p Policy) Delay(attempts int) time.Duration {
+	delay := float64(p.Base) * math.Pow(p.Factor, float64(attempts))
+	if math.IsInf(delay, 0) || math.IsNaN(delay) || delay > float64(p.Cap) {
+		delay = float64(p.Cap)
+	}
+
+	// Subtracting keeps the delay under the cap and spreads retries in time.
+	if p.Jitter > 0 {
+		delay -= delay * min(p.Jitter, 1) * rand.Float64()
+	}
+
+	return time.Duration(delay)
+}
@@ -0,0 +1,37 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelay(t *testing.T) {
+	p := Policy{Base: time.Second, Factor: 2, Cap: time.Minute}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{5, 32 * time.Second},
+		{6, time.Minute},
+		{10000, time.Minute},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, p.Delay(tt.attempts), "attempts: %d", tt.attempts)
+	}
+}
+
+func TestDelayJitter(t *testing.T) {
+	p := Policy{Base: time.Second, Factor: 2, Cap: time.Minute, Jitter: 0.5}
+
+	for attempts := 0; attempts < 10; attempts++ {
+		want := Policy{Base: p.Base, Factor: p.Factor, Cap: p.Cap}.Delay(attempts)
+		got := p.Delay(attempts)
+		assert.LessOrEqual(t, got, want)
+		assert.GreaterOrEqual(t, got, want/2)
+	}
+}
@@ -0,0 +1,159 @@
+// Package breaker implements the circuit breaker
+// stopping calls to the failing dependency.
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned instead of calling the dependency while the circuit is open.
+var ErrOpen = errors.New("circuit breaker is open")
+
+type State int
+
+const (
+	// Closed circuit lets all the calls through.
+	Closed State = iota
+	// Open circuit rejects all the calls.
+	Open
+	// HalfOpen circuit lets a limited number of trial calls through.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+type Settings struct {
+	// Number of consecutive failures opening the circuit.
+	FailureThreshold int
+	// Time the circuit stays open before trial calls are let through.
+	OpenTimeout time.Duration
+	// Number of successful trial calls closing the circuit.
+	SuccessThreshold int
+	// Called on each state change, if not nil.
+	OnStateChange func(from, to State)
+}
+
+type Breaker struct {
+	mu        sync.Mutex
+	settings  Settings
+	state     State
+	failures  int
+	successes int
+	trials    int
+	openedAt  time.Time
+}
+
+func New(settings Settings) *Breaker {
+	settings.FailureThreshold = max(settings.FailureThreshold, 1)
+	settings.SuccessThreshold = max(settings.SuccessThreshold, 1)
+
+	return &Breaker{settings: settings}
+}
+
+// State returns the current state of the circuit.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	from, to := b.refresh()
+	state := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+
+	return state
+}
+
+// Allow returns ErrOpen if the call is not allowed. Otherwise
+// the result of the call must be reported with Done.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	from, to := b.refresh()
+
+	var err error
+	switch b.state {
+	case Open:
+		err = ErrOpen
+	case HalfOpen:
+		if b.trials >= b.settings.SuccessThreshold {
+			err = ErrOpen
+		} else {
+			b.trials++
+		}
+	}
+	b.mu.Unlock()
+
+	b.notify(from, to)
+
+	return err
+}
+
+// Done reports the result of the allowed call.
+func (b *Breaker) Done(success bool) {
+	b.mu.Lock()
+
+	from, to := b.state, b.state
+
+	switch b.state {
+	case Closed:
+		if success {
+			b.failures = 0
+			break
+		}
+		b.failures++
+		if b.failures >= b.settings.FailureThreshold {
+			to = b.setState(Open)
+		}
+	case HalfOpen:
+		if !success {
+			to = b.setState(Open)
+			break
+		}
+		b.successes++
+		if b.successes >= b.settings.SuccessThreshold {
+			to = b.setState(Closed)
+		}
+	case Open:
+		// Late result of the call allowed before the circuit opened.
+	}
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// refresh lets trial calls through once the open timeout is over.
+func (b *Breaker) refresh() (from, to State) {
+	from = b.state
+	if b.state == Open && time.Since(b.openedAt) >= b.settings.OpenTimeout {
+		return from, b.setState(HalfOpen)
+	}
+	return from, from
+}
+
+func (b *Breaker) setState(state State) State {
+	b.state = state
+	b.failures = 0
+	b.successes = 0
+	b.trials = 0
+	if state == Open {
+		b.openedAt = time.Now()
+	}
+	return state
+}
+
+func (b *Breaker) notify(from, to State) {
+	if from != to && b.settings.OnStateChange != nil {
+		b.settings.OnStateChange(from, to)
+	}
+}
@@ -0,0 +1,59 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreaker(t *testing.T) {
+	var transitions []string
+
+	b := New(Settings{
+		FailureThreshold: 2,
+		OpenTimeout:      20 * time.Millisecond,
+		SuccessThreshold: 2,
+		OnStateChange: func(from, to State) {
+			transitions = append(transitions, from.String()+" -> "+to.String())
+		},
+	})
+
+	// Success resets consecutive failures.
+	for _, success := range []bool{false, true, false} {
+		require.NoError(t, b.Allow())
+		b.Done(success)
+	}
+	assert.Equal(t, Closed, b.State())
+
+	// The second consecutive failure opens the circuit.
+	require.NoError(t, b.Allow())
+	b.Done(false)
+	assert.Equal(t, Open, b.State())
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+
+	// Trial calls are limited and a failed one opens the circuit again.
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, b.Allow())
+	require.NoError(t, b.Allow())
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+	b.Done(false)
+	assert.Equal(t, Open, b.State())
+
+	// Successful trial calls close the circuit.
+	time.Sleep(20 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, b.Allow())
+		b.Done(true)
+	}
+	assert.Equal(t, Closed, b.State())
+
+	assert.Equal(t, []string{
+		"closed -> open",
+		"open -> half-open",
+		"half-open -> open",
+		"open -> half-open",
+		"half-open -> closed",
+	}, transitions)
+}
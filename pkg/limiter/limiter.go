@@ -2,16 +2,23 @@ package limiter
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"golang.org/x/time/rate"
 )
 
+// Each successful call shrinks the throttled interval by this factor.
+const recoveryFactor = 0.9
+
 type DynamicRateLimiter struct {
 	limiter  *rate.Limiter
-	updates  chan rateParams
+	mu       sync.Mutex
+	resumeAt time.Time
 	interval time.Duration
 	burst    int
+	// Configured parameters to recover to after throttling.
+	base rateParams
 }
 
 type rateParams struct {
@@ -20,30 +27,101 @@ type rateParams struct {
 }
 
 func NewDynamicRateLimiter(interval time.Duration, burst int) *DynamicRateLimiter {
-	limiter := rate.NewLimiter(rate.Every(interval), burst)
-	updates := make(chan rateParams)
-	go func() {
-		for params := range updates {
-			limiter.SetLimit(rate.Every(params.interval))
-			limiter.SetBurst(params.burst)
-		}
-	}()
 	return &DynamicRateLimiter{
-		limiter:  limiter,
+		limiter:  rate.NewLimiter(rate.Every(interval), burst),
 		interval: interval,
 		burst:    burst,
-		updates:  updates,
+		base:     rateParams{interval: interval, burst: burst},
 	}
 }
 
+// Wait blocks until the pause is over and the limiter permits an event.
 func (drl *DynamicRateLimiter) Wait(ctx context.Context) error {
+	for {
+		drl.mu.Lock()
+		pause := time.Until(drl.resumeAt)
+		drl.mu.Unlock()
+
+		if pause <= 0 {
+			break
+		}
+
+		timer := time.NewTimer(pause)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+			// The pause could be prolonged meanwhile, so check it again.
+		}
+	}
+
 	return drl.limiter.Wait(ctx)
 }
 
+// Paused returns how long the waiters are still paused, zero if they aren't.
+func (drl *DynamicRateLimiter) Paused() time.Duration {
+	drl.mu.Lock()
+	defer drl.mu.Unlock()
+
+	return max(time.Until(drl.resumeAt), 0)
+}
+
 func (drl *DynamicRateLimiter) Allow() bool {
 	return drl.limiter.Allow()
 }
 
 func (drl *DynamicRateLimiter) Update(interval time.Duration, burst int) {
-	drl.updates <- rateParams{interval: interval, burst: burst}
+	drl.mu.Lock()
+	defer drl.mu.Unlock()
+
+	drl.update(interval, burst)
+}
+
+// Interval returns the current interval between events.
+func (drl *DynamicRateLimiter) Interval() time.Duration {
+	drl.mu.Lock()
+	defer drl.mu.Unlock()
+
+	return drl.interval
+}
+
+// Throttle pauses all the waiters for the given duration and slows
+// the limiter down to one event per interval. The limiter never gets
+// faster than it was configured, zero interval keeps the current one.
+func (drl *DynamicRateLimiter) Throttle(pause, interval time.Duration) {
+	drl.mu.Lock()
+	defer drl.mu.Unlock()
+
+	if resumeAt := time.Now().Add(pause); resumeAt.After(drl.resumeAt) {
+		drl.resumeAt = resumeAt
+	}
+
+	drl.update(max(interval, drl.interval, drl.base.interval), 1)
+}
+
+// Recover gradually brings the throttled limiter back
+// to the configured rate. It should be called on success.
+func (drl *DynamicRateLimiter) Recover() {
+	drl.mu.Lock()
+	defer drl.mu.Unlock()
+
+	if drl.interval == drl.base.interval && drl.burst == drl.base.burst {
+		return
+	}
+
+	interval := time.Duration(float64(drl.interval) * recoveryFactor)
+	if interval <= drl.base.interval {
+		drl.update(drl.base.interval, drl.base.burst)
+		return
+	}
+
+	drl.update(interval, drl.burst)
+}
+
+func (drl *DynamicRateLimiter) update(interval time.Duration, burst int) {
+	drl.interval = interval
+	drl.burst = burst
+	drl.limiter.SetLimit(rate.Every(interval))
+	drl.limiter.SetBurst(burst)
 }
@@ -0,0 +1,60 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottle(t *testing.T) {
+	drl := NewDynamicRateLimiter(time.Millisecond, 10)
+
+	drl.Throttle(50*time.Millisecond, time.Second)
+	assert.Equal(t, time.Second, drl.Interval())
+
+	start := time.Now()
+	require.NoError(t, drl.Wait(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestPaused(t *testing.T) {
+	drl := NewDynamicRateLimiter(time.Millisecond, 10)
+	assert.Zero(t, drl.Paused())
+
+	drl.Throttle(time.Minute, 0)
+	assert.InDelta(t, time.Minute, drl.Paused(), float64(time.Second))
+}
+
+func TestThrottleNeverSpeedsUp(t *testing.T) {
+	drl := NewDynamicRateLimiter(time.Second, 10)
+
+	drl.Throttle(0, time.Millisecond)
+	assert.Equal(t, time.Second, drl.Interval())
+}
+
+func TestWaitCanceledDuringPause(t *testing.T) {
+	drl := NewDynamicRateLimiter(time.Millisecond, 10)
+	drl.Throttle(time.Minute, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, drl.Wait(ctx), context.DeadlineExceeded)
+}
+
+func TestRecover(t *testing.T) {
+	drl := NewDynamicRateLimiter(time.Second, 10)
+	drl.Throttle(0, 2*time.Second)
+
+	drl.Recover()
+	assert.Equal(t, 1800*time.Millisecond, drl.Interval())
+
+	for i := 0; i < 10; i++ {
+		drl.Recover()
+	}
+	assert.Equal(t, time.Second, drl.Interval())
+	assert.Equal(t, 10, drl.limiter.Burst())
+}